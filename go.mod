@@ -1,9 +1,10 @@
-module github.com/mfgmateus/hyperliquid-go-sdk/v2
+module github.com/mfgmateus/hyperliquid-go-sdk/v3
 
 go 1.20
 
 require (
 	github.com/ethereum/go-ethereum v1.13.14
+	github.com/gorilla/websocket v1.5.1
 	github.com/stretchr/testify v1.8.4
 	github.com/vmihailenco/msgpack/v5 v5.4.1
 )
@@ -24,6 +25,7 @@ require (
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	golang.org/x/crypto v0.17.0 // indirect
 	golang.org/x/exp v0.0.0-20231110203233-9a3e6036ecaa // indirect
+	golang.org/x/net v0.18.0 // indirect
 	golang.org/x/sync v0.5.0 // indirect
 	golang.org/x/sys v0.16.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -0,0 +1,67 @@
+package hyperliquid
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ApproveAgent generates an agent key and approves it to trade for masterAddress, which signs the approval.
+// A named agent replaces the previous agent with the same name, an unnamed one replaces the previous unnamed agent.
+func (e *ExchangeImpl) ApproveAgent(ctx context.Context, masterAddress string, agentName string) (*ApproveAgentResponse, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	agentAddress := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	timestamp, err := e.nonceFor(masterAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	action := ApproveAgentAction{
+		Type:         "approveAgent",
+		AgentAddress: agentAddress,
+		AgentName:    agentName,
+		Nonce:        timestamp,
+	}
+
+	response, err := e.postUserSignedAction(ctx, masterAddress, action, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ApproveAgentResponse{
+		TransferResponse: *response,
+		AgentAddress:     agentAddress,
+		AgentKey:         key,
+	}, nil
+}
+
+// ApproveBuilderFee allows builder to charge up to maxFee, in tenths of a basis point, on the orders of address
+func (e *ExchangeImpl) ApproveBuilderFee(ctx context.Context, address string, builder string, maxFee int) (*TransferResponse, error) {
+	timestamp, err := e.nonceFor(address)
+	if err != nil {
+		return nil, err
+	}
+
+	action := ApproveBuilderFeeAction{
+		Type:       "approveBuilderFee",
+		MaxFeeRate: strconv.FormatFloat(float64(maxFee)/1000, 'f', -1, 64) + "%",
+		Builder:    strings.ToLower(builder),
+		Nonce:      timestamp,
+	}
+
+	return e.postUserSignedAction(ctx, address, action, timestamp)
+}
+
+// agentOf returns the address signing the L1 actions of address, which is its agent when one is set with WithAgent
+func (e *ExchangeImpl) agentOf(address string) string {
+	if agent, ok := e.agents[strings.ToLower(address)]; ok {
+		return agent
+	}
+	return address
+}
@@ -4,13 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 )
 
 type API interface {
-	Post(context context.Context, path string, payload any) any
+	Post(context context.Context, path string, payload any) (json.RawMessage, error)
 	IsMainnet() bool
 }
 
@@ -29,33 +30,57 @@ func NewApiDefault(baseUrl string, logger Logger) API {
 	}
 }
 
-func (a *APIDefault) Post(ctx context.Context, path string, payload any) any {
+func (a *APIDefault) Post(ctx context.Context, path string, payload any) (json.RawMessage, error) {
 	apiUrl := fmt.Sprintf("%s%s", a.baseUrl, path)
-	body, _ := json.Marshal(payload)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
 
 	a.logger.LogInfo(ctx, fmt.Sprintf("Request body is %s", body))
 
 	bodyReader := bytes.NewReader(body)
-	req, _ := http.NewRequestWithContext(ctx, "POST", apiUrl, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiUrl, bodyReader)
+	if err != nil {
+		return nil, &TransportError{Path: path, Err: err}
+	}
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := a.httpClient.Do(req)
 	if err != nil {
-		panic(err)
+		return nil, &TransportError{Path: path, Err: err}
 	}
-	var result any
+	defer resp.Body.Close()
+
 	a.logger.LogInfo(ctx, fmt.Sprintf("Resp status: %s", resp.Status))
 
-	// TODO: is this required? All subsequent calls marshal this so that it gets unmarshal-ed in the correct struct afterwards
-	// 		 this is creating friction (1), and the SDK should not panic (2)
-	bytes, _ := io.ReadAll(io.Reader(resp.Body))
-	errConversion := json.Unmarshal(bytes, &result)
-	if errConversion != nil {
-		a.logger.LogErr(ctx, fmt.Sprintf("Failed to parse response body: %s", bytes), errConversion)
-		panic("Failed to parse response body")
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &TransportError{Path: path, Err: err}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &HTTPStatusError{Path: path, StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
-	return result
+	if !json.Valid(respBody) {
+		err = &DecodeError{Body: respBody, Err: errors.New("invalid json")}
+		a.logger.LogErr(ctx, fmt.Sprintf("Failed to parse response body: %s", respBody), err)
+		return nil, err
+	}
+	return respBody, nil
 }
 
 func (a *APIDefault) IsMainnet() bool {
 	return a.baseUrl == MainnetUrl
 }
+
+// postInfo sends an info request and decodes the response into T
+func postInfo[T any](ctx context.Context, cli API, request any) (T, error) {
+	var result T
+	res, err := cli.Post(ctx, "/info", request)
+	if err != nil {
+		return result, err
+	}
+	if err = json.Unmarshal(res, &result); err != nil {
+		return result, &DecodeError{Body: res, Err: err}
+	}
+	return result, nil
+}
@@ -0,0 +1,157 @@
+package hyperliquid
+
+import (
+	"math"
+	"strconv"
+)
+
+type L2Level struct {
+	Px string `json:"px"`
+	Sz string `json:"sz"`
+	N  int    `json:"n"`
+}
+
+// L2Book is a snapshot of the book, Levels[0] holds the bids and Levels[1] the asks, both from best to worst
+type L2Book struct {
+	Coin   string       `json:"coin"`
+	Time   int64        `json:"time"`
+	Levels [2][]L2Level `json:"levels"`
+}
+
+func (l L2Level) Price() (float64, error) {
+	return strconv.ParseFloat(l.Px, 64)
+}
+
+func (l L2Level) Size() (float64, error) {
+	return strconv.ParseFloat(l.Sz, 64)
+}
+
+func (b L2Book) Bids() []L2Level {
+	return b.Levels[0]
+}
+
+func (b L2Book) Asks() []L2Level {
+	return b.Levels[1]
+}
+
+func (b L2Book) BestBid() (L2Level, bool) {
+	if len(b.Bids()) == 0 {
+		return L2Level{}, false
+	}
+	return b.Bids()[0], true
+}
+
+func (b L2Book) BestAsk() (L2Level, bool) {
+	if len(b.Asks()) == 0 {
+		return L2Level{}, false
+	}
+	return b.Asks()[0], true
+}
+
+func (b L2Book) bestPrices() (float64, float64, error) {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+	if !okBid || !okAsk {
+		return 0, 0, ErrEmptyBook
+	}
+	bidPx, err := bid.Price()
+	if err != nil {
+		return 0, 0, err
+	}
+	askPx, err := ask.Price()
+	if err != nil {
+		return 0, 0, err
+	}
+	return bidPx, askPx, nil
+}
+
+func (b L2Book) Mid() (float64, error) {
+	bidPx, askPx, err := b.bestPrices()
+	if err != nil {
+		return 0, err
+	}
+	return (bidPx + askPx) / 2, nil
+}
+
+func (b L2Book) Spread() (float64, error) {
+	bidPx, askPx, err := b.bestPrices()
+	if err != nil {
+		return 0, err
+	}
+	return askPx - bidPx, nil
+}
+
+// side returns the levels an order would take liquidity from, asks for buys and bids for sells
+func (b L2Book) side(isBuy bool) []L2Level {
+	if isBuy {
+		return b.Asks()
+	}
+	return b.Bids()
+}
+
+// DepthAtPrice is the size an order can fill at px or better
+func (b L2Book) DepthAtPrice(isBuy bool, px float64) (float64, error) {
+	depth := 0.0
+	for _, level := range b.side(isBuy) {
+		levelPx, err := level.Price()
+		if err != nil {
+			return 0, err
+		}
+		if (isBuy && levelPx > px) || (!isBuy && levelPx < px) {
+			break
+		}
+		sz, err := level.Size()
+		if err != nil {
+			return 0, err
+		}
+		depth += sz
+	}
+	return depth, nil
+}
+
+// ImpactPrices walks the book to fill sz, returning the average fill price and the worst level price reached
+func (b L2Book) ImpactPrices(isBuy bool, sz float64) (vwap float64, worstPx float64, err error) {
+	if !(sz > 0) {
+		return 0, 0, ErrInvalidSize
+	}
+	remaining := sz
+	notional := 0.0
+	for _, level := range b.side(isBuy) {
+		levelPx, err := level.Price()
+		if err != nil {
+			return 0, 0, err
+		}
+		levelSz, err := level.Size()
+		if err != nil {
+			return 0, 0, err
+		}
+		filled := math.Min(remaining, levelSz)
+		notional += filled * levelPx
+		remaining -= filled
+		worstPx = levelPx
+		if remaining <= 0 {
+			return notional / sz, worstPx, nil
+		}
+	}
+	return 0, 0, ErrInsufficientLiquidity
+}
+
+// VWAP is the average price to fill sz against the book
+func (b L2Book) VWAP(isBuy bool, sz float64) (float64, error) {
+	vwap, _, err := b.ImpactPrices(isBuy, sz)
+	return vwap, err
+}
+
+// SlippageToFill is the relative distance between the mid and the worst price needed to fill sz,
+// it can be used as OpenRequest.Slippage instead of DefaultSlippage
+func (b L2Book) SlippageToFill(isBuy bool, sz float64) (float64, error) {
+	mid, err := b.Mid()
+	if err != nil {
+		return 0, err
+	}
+	_, worstPx, err := b.ImpactPrices(isBuy, sz)
+	if err != nil {
+		return 0, err
+	}
+	return math.Abs(worstPx-mid) / mid, nil
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math"
-	"math/big"
 	"strconv"
 	"strings"
 
@@ -49,39 +48,71 @@ func ModifyOrderWiresToModifyOrderAction(orders []ModifyOrderWire) ModifyOrdersA
 	}
 }
 
+// Deprecated: OrderReqToWire panics for coins missing from the meta table, use OrderRequestToWire instead
 func OrderReqToWire(req OrderRequest, meta map[string]AssetInfo) OrderWire {
+	wire, err := OrderRequestToWire(req, meta)
+	if err != nil {
+		panic(err.Error())
+	}
+	return wire
+}
+
+func OrderRequestToWire(req OrderRequest, meta map[string]AssetInfo) (OrderWire, error) {
 	info, ok := meta[req.Coin]
 	if !ok {
-		panic(fmt.Sprintf("coin (%v) is not defined in meta table", req.Coin))
+		return OrderWire{}, &UnknownAssetError{Coin: req.Coin}
+	}
+	if err := checkPrice(req.LimitPx); err != nil {
+		return OrderWire{}, err
 	}
 	return OrderWire{
 		Asset:      info.AssetId,
 		IsBuy:      req.IsBuy,
-		LimitPx:    PriceToWire(req.LimitPx, info.SzDecimals),
+		LimitPx:    info.PriceToWire(req.LimitPx),
 		SizePx:     SizeToWire(req.Sz, info.SzDecimals),
 		ReduceOnly: req.ReduceOnly,
 		OrderType:  OrderTypeToWire(req.OrderType),
 		Cloid:      req.Cloid,
-	}
+	}, nil
 }
 
+// Deprecated: ModifyOrderReqToWire panics for coins missing from the meta table, use ModifyOrderRequestToWire instead
 func ModifyOrderReqToWire(req ModifyOrderRequest, meta map[string]AssetInfo) ModifyOrderWire {
+	wire, err := ModifyOrderRequestToWire(req, meta)
+	if err != nil {
+		panic(err.Error())
+	}
+	return wire
+}
+
+func ModifyOrderRequestToWire(req ModifyOrderRequest, meta map[string]AssetInfo) (ModifyOrderWire, error) {
 	info, ok := meta[req.Coin]
 	if !ok {
-		panic(fmt.Sprintf("coin (%v) is not defined in meta table", req.Coin))
+		return ModifyOrderWire{}, &UnknownAssetError{Coin: req.Coin}
+	}
+	if err := checkPrice(req.LimitPx); err != nil {
+		return ModifyOrderWire{}, err
 	}
 	return ModifyOrderWire{
 		OidOrCloid: req.OidOrCloid,
 		Order: OrderWire{
 			Asset:      info.AssetId,
 			IsBuy:      req.IsBuy,
-			LimitPx:    PriceToWire(req.LimitPx, info.SzDecimals),
+			LimitPx:    info.PriceToWire(req.LimitPx),
 			SizePx:     SizeToWire(req.Sz, info.SzDecimals),
 			ReduceOnly: req.ReduceOnly,
 			OrderType:  OrderTypeToWire(req.OrderType),
 			Cloid:      req.Cloid,
 		},
+	}, nil
+}
+
+// checkPrice returns an error for prices which can't be formatted for the wire
+func checkPrice(px float64) error {
+	if math.IsNaN(px) || math.IsInf(px, 0) || px < 0 || px >= math.MaxInt64 {
+		return fmt.Errorf("invalid price %v", px)
 	}
+	return nil
 }
 
 func OrderTypeToWire(orderType OrderType) OrderTypeWire {
@@ -130,36 +161,37 @@ func int64ToFixedSize(x int64, decimals int) string {
 	return str
 }
 
+const MaxPerpDecimals = 6
+const MaxSpotDecimals = 8
+
 func PriceToWire(x float64, szDecimals int) string {
-	// Prices can have up to 5 significant figures, but no more than MAX_DECIMALS - szDecimals decimal places
-	// where MAX_DECIMALS is 6 for perps and 8 for spot.
-	// This only treats perps case
+	return priceToWire(x, szDecimals, MaxPerpDecimals)
+}
 
-	bigf := big.NewFloat(x)
+func SpotPriceToWire(x float64, szDecimals int) string {
+	return priceToWire(x, szDecimals, MaxSpotDecimals)
+}
 
-	maxDecSz := 0
-	intPart, _ := bigf.Int64()
+func priceToWire(x float64, szDecimals int, maxDecimals int) string {
+	// Prices can have up to 5 significant figures, but no more than MAX_DECIMALS - szDecimals decimal places
+	// where MAX_DECIMALS is 6 for perps and 8 for spot. Integer prices are always valid, whatever their size.
+	if x <= 0 {
+		return "0"
+	}
 
-	// all significant figures are after the decimal point
-	if intPart == 0 {
-		// default limit is MAX_DECIMALS - szDecimals
-		maxDecSz = 6 - szDecimals
+	// digits before the decimal point, negative for the zeros after it, e.g. 2 for 12.3 and -2 for 0.00123
+	intSize := int(math.Floor(math.Log10(x))) + 1
 
-		if x >= 0.1 && maxDecSz < 5 {
-			// limit to 5 significant figures, because first digit after the decimal point is != 0
-			maxDecSz = 5
-		}
-	} else {
-		intSize := len(strconv.FormatInt(intPart, 10))
-		if intSize > 5 {
-			panic("price too big. at most 5 decimals are allowed")
-		} else {
-			maxDecSz = 5 - intSize
-		}
+	decimals := 5 - intSize
+	if decimals > maxDecimals-szDecimals {
+		decimals = maxDecimals - szDecimals
+	}
+	if decimals < 0 {
+		decimals = 0
 	}
 
-	exp := math.Pow(10.0, float64(maxDecSz))
-	return int64ToFixedSize(int64(x*exp), maxDecSz)
+	exp := math.Pow(10.0, float64(decimals))
+	return int64ToFixedSize(int64(x*exp), decimals)
 }
 
 func SizeToWire(x float64, szDecimals int) string {
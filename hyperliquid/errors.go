@@ -0,0 +1,100 @@
+package hyperliquid
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoPosition is returned when an action requires an open position for the asset and none is found
+var ErrNoPosition = errors.New("no position found")
+
+// ErrEmptyBook is returned when a book side has no levels
+var ErrEmptyBook = errors.New("book has no bids or no asks")
+
+// ErrInsufficientLiquidity is returned when the book doesn't have enough size to fill an order
+var ErrInsufficientLiquidity = errors.New("not enough liquidity in the book")
+
+// ErrInvalidSize is returned when a size walked through the book isn't positive
+var ErrInvalidSize = errors.New("size must be positive")
+
+// TransportError is returned when the request could not be sent or the response could not be read
+type TransportError struct {
+	Path string
+	Err  error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("request to %s failed: %s", e.Path, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatusError is returned when the API answers with a non 2xx status code
+type HTTPStatusError struct {
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("request to %s returned status %d: %s", e.Path, e.StatusCode, e.Body)
+}
+
+// DecodeError is returned when a response body can't be parsed into the expected structure
+type DecodeError struct {
+	Body []byte
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to parse response body %s: %s", e.Body, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// SigningError is returned when an action can't be packed or signed
+type SigningError struct {
+	Address string
+	Err     error
+}
+
+func (e *SigningError) Error() string {
+	return fmt.Sprintf("failed to sign request for %s: %s", e.Address, e.Err)
+}
+
+func (e *SigningError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownAssetError is returned when a coin is not defined in the meta table
+type UnknownAssetError struct {
+	Coin string
+}
+
+func (e *UnknownAssetError) Error() string {
+	return fmt.Sprintf("coin (%v) is not defined in meta table", e.Coin)
+}
+
+// OrderError is reported for a single order the exchange rejected within an otherwise accepted action
+type OrderError struct {
+	Message string
+}
+
+func (e *OrderError) Error() string {
+	return fmt.Sprintf("order rejected: %s", e.Message)
+}
+
+// ExchangeError is returned when the exchange rejects an action as a whole, e.g. invalid signature
+// or insufficient margin. Errors for individual orders are reported in the response statuses instead.
+type ExchangeError struct {
+	Status  string
+	Message string
+}
+
+func (e *ExchangeError) Error() string {
+	return fmt.Sprintf("exchange returned status %s: %s", e.Status, e.Message)
+}
@@ -5,11 +5,11 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -19,19 +19,46 @@ import (
 )
 
 type ExchangeApi interface {
-	MarketOpen(context context.Context, req OpenRequest) *PlaceOrderResponse
-	MarketClose(context context.Context, req CloseRequest) *PlaceOrderResponse
-	Trigger(context context.Context, req TriggerRequest) *PlaceOrderResponse
-	Order(context context.Context, address string, req OrderRequest, grouping Grouping) *PlaceOrderResponse
-	Points(context context.Context, address string) PointsResponse
-	FindOrder(context context.Context, address string, cloid string) OrderResponse
-	CancelOrder(context context.Context, address string, coin string, cloid string) *CancelOrderResponse
-	CancelOrderByOid(context context.Context, address string, coin string, oid int64) *CancelOrderResponse
-	ModifyOrder(ctx context.Context, address string, request ModifyOrderRequest) *ModifyOrderResponse
-	UpdateLeverage(context context.Context, req UpdateLeverageRequest) any
-	GetMktPx(context context.Context, coin string) float64
-	GetUserFills(context context.Context, address string) []OrderFill
-	Withdraw(context context.Context, request WithdrawRequest) *WithdrawResponse
+	MarketOpen(context context.Context, req OpenRequest, opts ...ActionOption) (*PlaceOrderResponse, error)
+	MarketClose(context context.Context, req CloseRequest, opts ...ActionOption) (*PlaceOrderResponse, error)
+	Trigger(context context.Context, req TriggerRequest, opts ...ActionOption) (*PlaceOrderResponse, error)
+	Order(context context.Context, address string, req OrderRequest, grouping Grouping, opts ...ActionOption) (*PlaceOrderResponse, error)
+	BulkOrders(context context.Context, address string, requests []OrderRequest, grouping Grouping, opts ...ActionOption) (*PlaceOrderResponse, error)
+	Points(context context.Context, address string) (PointsResponse, error)
+	FindOrder(context context.Context, address string, cloid string) (OrderResponse, error)
+	CancelOrder(context context.Context, address string, coin string, cloid string, opts ...ActionOption) (*CancelOrderResponse, error)
+	CancelOrderByOid(context context.Context, address string, coin string, oid int64, opts ...ActionOption) (*CancelOrderResponse, error)
+	BulkCancel(ctx context.Context, address string, requests []CancelRequest, opts ...ActionOption) ([]CancelResult, error)
+	CancelAll(ctx context.Context, address string, coinFilter []string, opts ...ActionOption) ([]CancelResult, error)
+	ModifyOrder(ctx context.Context, address string, request ModifyOrderRequest, opts ...ActionOption) (*ModifyOrderResponse, error)
+	BulkModify(ctx context.Context, address string, requests []ModifyOrderRequest, opts ...ActionOption) (*ModifyOrderResponse, error)
+	UpdateLeverage(context context.Context, req UpdateLeverageRequest, opts ...ActionOption) (*ActionResponse, error)
+	UpdateIsolatedMargin(ctx context.Context, address string, coin string, isBuy bool, usdDelta float64, opts ...ActionOption) (*ActionResponse, error)
+	TargetLiquidationPx(ctx context.Context, address string, coin string, liquidationPx float64, opts ...ActionOption) (*ActionResponse, error)
+	TwapOrder(ctx context.Context, address string, coin string, isBuy bool, sz float64, minutes int, randomize bool, reduceOnly bool, opts ...ActionOption) (*TwapOrderResponse, error)
+	TwapCancel(ctx context.Context, address string, coin string, twapId int64, opts ...ActionOption) (*TwapCancelResponse, error)
+	ScheduleCancel(ctx context.Context, address string, at *time.Time, opts ...ActionOption) (*ActionResponse, error)
+	ScheduleCancelHeartbeat(ctx context.Context, address string, timeout time.Duration, interval time.Duration, opts ...ActionOption) error
+	GetMktPx(context context.Context, coin string) (float64, error)
+	GetUserFills(context context.Context, address string) ([]OrderFill, error)
+	Withdraw(context context.Context, request WithdrawRequest) (*WithdrawResponse, error)
+	UsdSend(ctx context.Context, request UsdSendRequest) (*TransferResponse, error)
+	ApproveAgent(ctx context.Context, masterAddress string, agentName string) (*ApproveAgentResponse, error)
+	ApproveBuilderFee(ctx context.Context, address string, builder string, maxFee int) (*TransferResponse, error)
+	SpotSend(ctx context.Context, request SpotSendRequest) (*TransferResponse, error)
+	UsdClassTransfer(ctx context.Context, request UsdClassTransferRequest, opts ...ActionOption) (*TransferResponse, error)
+	SubAccountTransfer(ctx context.Context, request SubAccountTransferRequest) (*TransferResponse, error)
+	TokenDelegate(ctx context.Context, address string, validator string, wei uint64, isUndelegate bool) (*TransferResponse, error)
+	CDeposit(ctx context.Context, address string, wei uint64) (*TransferResponse, error)
+	CWithdraw(ctx context.Context, address string, wei uint64) (*TransferResponse, error)
+	VaultTransfer(ctx context.Context, address string, vault string, isDeposit bool, usd float64) (*TransferResponse, error)
+	CreateSubAccount(ctx context.Context, address string, name string) (string, error)
+	SubAccountModify(ctx context.Context, address string, subAccount string, name string) (*ActionResponse, error)
+	Noop(ctx context.Context, address string, nonce int64, opts ...ActionOption) (*ActionResponse, error)
+	ResolveRequest(ctx context.Context, address string, request ExchangeRequest) (*Resolution, error)
+	ConvertToMultiSigUser(ctx context.Context, address string, authorizedUsers []string, threshold int) (*TransferResponse, error)
+	NewMultiSigTransaction(multiSigUser string, outerSigner string, action any, nonce int64, opts ...ActionOption) (*MultiSigTransaction, error)
+	SendMultiSig(ctx context.Context, tx *MultiSigTransaction) (json.RawMessage, error)
 }
 
 type ExchangeImpl struct {
@@ -40,27 +67,43 @@ type ExchangeImpl struct {
 	meta       map[string]AssetInfo
 	keyManager *KeyManager
 	logger     Logger
+	agents     map[string]string
+	nonces     NonceSource
+	expiry     time.Duration
 }
 
-func NewExchange(cli *API, manager *KeyManager, logger Logger) ExchangeApi {
+func NewExchange(cli *API, manager *KeyManager, logger Logger, opts ...ExchangeOption) (ExchangeApi, error) {
 
 	infoApi := NewInfoApi(cli)
-	meta := BuildMetaMap(infoApi)
+	meta, err := BuildMetaMap(context.Background(), infoApi)
+	if err != nil {
+		return nil, err
+	}
 
-	return &ExchangeImpl{
+	exchange := &ExchangeImpl{
 		infoApi:    infoApi,
 		meta:       meta,
 		cli:        cli,
 		keyManager: manager,
 		logger:     logger,
+		nonces:     defaultNonceSource,
 	}
+	for _, opt := range opts {
+		opt(exchange)
+	}
+
+	return exchange, nil
 }
 
-func (e *ExchangeImpl) SlippagePrice(ctx context.Context, coin string, isBuy bool, slippage float64, px *float64) float64 {
+// SlippagePrice applies slippage to px, or to the price of coin from the source set WithPriceSource, the mid by default
+func (e *ExchangeImpl) SlippagePrice(ctx context.Context, coin string, isBuy bool, slippage float64, px *float64, opts ...ActionOption) (float64, error) {
 
 	if px == nil || *px <= 0.0 {
+		parsed, err := e.referencePx(ctx, coin, buildActionOptions(opts).priceSource)
+		if err != nil {
+			return 0, err
+		}
 		px = new(float64)
-		parsed := e.GetMktPx(ctx, coin)
 		*px = parsed
 	}
 
@@ -68,11 +111,28 @@ func (e *ExchangeImpl) SlippagePrice(ctx context.Context, coin string, isBuy boo
 
 }
 
-func (e *ExchangeImpl) GetMktPx(ctx context.Context, coin string) float64 {
+func (e *ExchangeImpl) referencePx(ctx context.Context, coin string, source PriceSource) (float64, error) {
+	switch source {
+	case PriceSourceMid, "":
+		return e.GetMktPx(ctx, coin)
+	case PriceSourceMark:
+		return e.infoApi.GetMarkPx(ctx, coin)
+	case PriceSourceOracle:
+		return e.infoApi.GetOraclePx(ctx, coin)
+	default:
+		return 0, fmt.Errorf("unknown price source %q", source)
+	}
+}
+
+func (e *ExchangeImpl) GetMktPx(ctx context.Context, coin string) (float64, error) {
+	// spot pairs may be referenced by their tokens, while mids are keyed by the pair name
+	if info, ok := e.meta[coin]; ok && info.IsSpot {
+		coin = info.Name
+	}
 	return e.infoApi.GetMktPx(ctx, coin)
 }
 
-func (e *ExchangeImpl) CalculateSlippage(ctx context.Context, isBuy bool, px *float64, slippage float64) float64 {
+func (e *ExchangeImpl) CalculateSlippage(ctx context.Context, isBuy bool, px *float64, slippage float64) (float64, error) {
 
 	if isBuy {
 		*px = *px * (1 + slippage)
@@ -87,11 +147,11 @@ func (e *ExchangeImpl) CalculateSlippage(ctx context.Context, isBuy bool, px *fl
 	pxFloat, err := strconv.ParseFloat(pxStr, 64)
 	if err != nil {
 		e.logger.LogErr(ctx, "Error parsing float", err)
-		panic("Failed to parse")
+		return 0, err
 	}
 
 	// Round the float to 6 decimal places
-	return pxFloat
+	return pxFloat, nil
 }
 
 func IsBuy(szi float64) bool {
@@ -102,10 +162,13 @@ func IsBuy(szi float64) bool {
 	}
 }
 
-func (e *ExchangeImpl) MarketOpen(ctx context.Context, req OpenRequest) *PlaceOrderResponse {
+func (e *ExchangeImpl) MarketOpen(ctx context.Context, req OpenRequest, opts ...ActionOption) (*PlaceOrderResponse, error) {
 
 	slippage := GetSlippage(req.Slippage)
-	finalPx := e.SlippagePrice(ctx, req.Coin, req.IsBuy, slippage, req.Px)
+	finalPx, err := e.SlippagePrice(ctx, req.Coin, req.IsBuy, slippage, req.Px, opts...)
+	if err != nil {
+		return nil, err
+	}
 
 	orderType := OrderType{
 		Limit: &LimitOrderType{
@@ -123,16 +186,20 @@ func (e *ExchangeImpl) MarketOpen(ctx context.Context, req OpenRequest) *PlaceOr
 		Cloid:      req.Cloid,
 	}
 
-	return e.Order(ctx, req.Address, orderReq, GroupingNa)
+	return e.Order(ctx, req.Address, orderReq, GroupingNa, opts...)
 
 }
 
-func (e *ExchangeImpl) MarketClose(ctx context.Context, req CloseRequest) *PlaceOrderResponse {
+func (e *ExchangeImpl) MarketClose(ctx context.Context, req CloseRequest, opts ...ActionOption) (*PlaceOrderResponse, error) {
 
-	positions := e.infoApi.GetUserState(ctx, req.Address).AssetPositions
+	options := buildActionOptions(opts)
+	state, err := e.infoApi.GetUserState(ctx, options.account(req.Address))
+	if err != nil {
+		return nil, err
+	}
 	slippage := GetSlippage(req.Slippage)
 
-	for _, position := range positions {
+	for _, position := range state.AssetPositions {
 
 		item := position.Position
 
@@ -150,7 +217,10 @@ func (e *ExchangeImpl) MarketClose(ctx context.Context, req CloseRequest) *Place
 
 		isBuy := IsBuy(szi)
 
-		finalPx := e.SlippagePrice(ctx, req.Coin, isBuy, slippage, req.Px)
+		finalPx, err := e.SlippagePrice(ctx, req.Coin, isBuy, slippage, req.Px, opts...)
+		if err != nil {
+			return nil, err
+		}
 
 		orderType := OrderType{
 			Limit: &LimitOrderType{
@@ -168,27 +238,23 @@ func (e *ExchangeImpl) MarketClose(ctx context.Context, req CloseRequest) *Place
 			Cloid:      req.Cloid,
 		}
 
-		return e.Order(ctx, req.Address, orderReq, GroupingNa)
+		return e.Order(ctx, req.Address, orderReq, GroupingNa, opts...)
 
 	}
 
-	err := fmt.Sprintf("No position found for asset %s", req.Coin)
-	return buildFailedResponse(err)
-}
-
-func buildFailedResponse(err string) *PlaceOrderResponse {
-	return &PlaceOrderResponse{
-		Status:      "err",
-		ResponseErr: &err,
-	}
+	return nil, fmt.Errorf("%w for asset %s", ErrNoPosition, req.Coin)
 }
 
-func (e *ExchangeImpl) Trigger(ctx context.Context, req TriggerRequest) *PlaceOrderResponse {
+func (e *ExchangeImpl) Trigger(ctx context.Context, req TriggerRequest, opts ...ActionOption) (*PlaceOrderResponse, error) {
 
 	slippage := GetSlippage(req.Slippage)
-	positions := e.infoApi.GetUserState(ctx, req.Address).AssetPositions
+	options := buildActionOptions(opts)
+	state, err := e.infoApi.GetUserState(ctx, options.account(req.Address))
+	if err != nil {
+		return nil, err
+	}
 
-	for _, position := range positions {
+	for _, position := range state.AssetPositions {
 
 		item := position.Position
 
@@ -204,7 +270,10 @@ func (e *ExchangeImpl) Trigger(ctx context.Context, req TriggerRequest) *PlaceOr
 			*sz = math.Abs(szi)
 		}
 		isBuy := IsBuy(szi)
-		finalPx := e.SlippagePrice(ctx, req.Coin, isBuy, slippage, req.Px)
+		finalPx, err := e.SlippagePrice(ctx, req.Coin, isBuy, slippage, req.Px, opts...)
+		if err != nil {
+			return nil, err
+		}
 
 		orderType := OrderType{
 			Trigger: &req.Trigger,
@@ -220,12 +289,11 @@ func (e *ExchangeImpl) Trigger(ctx context.Context, req TriggerRequest) *PlaceOr
 			Cloid:      req.Cloid,
 		}
 
-		return e.Order(ctx, req.Address, orderReq, GroupingTpSl)
+		return e.Order(ctx, req.Address, orderReq, GroupingTpSl, opts...)
 
 	}
 
-	err := fmt.Sprintf("No position found for asset %s", req.Coin)
-	return buildFailedResponse(err)
+	return nil, fmt.Errorf("%w for asset %s", ErrNoPosition, req.Coin)
 }
 
 func GetSlippage(sl *float64) float64 {
@@ -237,103 +305,101 @@ func GetSlippage(sl *float64) float64 {
 	return slippage
 }
 
-func (e *ExchangeImpl) Order(context context.Context, address string, req OrderRequest, grouping Grouping) *PlaceOrderResponse {
-	return e.BulkOrders(context, address, []OrderRequest{req}, grouping)
+func (e *ExchangeImpl) Order(context context.Context, address string, req OrderRequest, grouping Grouping, opts ...ActionOption) (*PlaceOrderResponse, error) {
+	return e.BulkOrders(context, address, []OrderRequest{req}, grouping, opts...)
 
 }
 
-func (e *ExchangeImpl) Points(context context.Context, address string) PointsResponse {
+func (e *ExchangeImpl) Points(context context.Context, address string) (PointsResponse, error) {
 
 	timestamp := int64(1731334407)
 
-	v, r, s := e.SignPointsAction(context, address, timestamp, (*e.cli).IsMainnet())
+	v, r, s, err := e.SignPointsAction(context, address, timestamp, (*e.cli).IsMainnet())
+	if err != nil {
+		return PointsResponse{}, err
+	}
 
+	_, chainId := e.chain()
 	request := PointsRequest{
 		User:      &address,
 		Typez:     "userPoints2",
 		Signature: ToTypedSig(r, s, v),
 		Timestamp: timestamp,
-		ChainId:   "0xa4b1",
+		ChainId:   chainId,
 	}
 
-	anyResult := (*e.cli).Post(context, "/info", request)
-	parsed, _ := json.Marshal(anyResult)
-	fmt.Printf("Res %s\n", parsed)
-	var result PointsResponse
-	_ = json.Unmarshal(parsed, &result)
-	return result
+	return postInfo[PointsResponse](context, *e.cli, request)
 }
 
-func (e *ExchangeImpl) BulkOrders(ctx context.Context, address string, requests []OrderRequest, grouping Grouping) *PlaceOrderResponse {
+func (e *ExchangeImpl) BulkOrders(ctx context.Context, address string, requests []OrderRequest, grouping Grouping, opts ...ActionOption) (*PlaceOrderResponse, error) {
 	var wires []OrderWire
 	for _, req := range requests {
-		wires = append(wires, OrderReqToWire(req, e.meta))
+		wire, err := OrderRequestToWire(req, e.meta)
+		if err != nil {
+			return nil, err
+		}
+		wires = append(wires, wire)
 	}
 
-	timestamp := GetNonce()
+	options := buildActionOptions(opts)
 	action := OrderWiresToOrderAction(wires, grouping)
+	action.Builder = options.builder
 
-	v, r, s := e.SignL1Action(ctx, address, action, timestamp, (*e.cli).IsMainnet())
-
-	payload := ExchangeRequest{
-		Action:       action,
-		Nonce:        timestamp,
-		Signature:    ToTypedSig(r, s, v),
-		VaultAddress: nil,
+	res, err := e.postL1Action(ctx, address, action, options)
+	if err != nil {
+		return nil, err
 	}
 
-	res := (*e.cli).Post(ctx, "/exchange", payload)
-	m, _ := json.Marshal(res)
-
-	e.logger.LogInfo(ctx, fmt.Sprintf("Response is %s", res))
-
-	response, err := unmarshalPlaceOrderResponse(m)
+	response, err := unmarshalPlaceOrderResponse(res)
 	if err != nil {
 		e.logger.LogErr(ctx, "failed to unmarshalPlaceOrderResponse", err)
-		return nil
+		return nil, &DecodeError{Body: res, Err: err}
+	}
+	if response.ResponseErr != nil {
+		return nil, &ExchangeError{Status: response.Status, Message: *response.ResponseErr}
 	}
 
-	return response
+	return response, nil
 }
 
-func (e *ExchangeImpl) ModifyOrder(ctx context.Context, address string, request ModifyOrderRequest) *ModifyOrderResponse {
-	return e.BulkModify(ctx, address, []ModifyOrderRequest{request})
+func (e *ExchangeImpl) ModifyOrder(ctx context.Context, address string, request ModifyOrderRequest, opts ...ActionOption) (*ModifyOrderResponse, error) {
+	return e.BulkModify(ctx, address, []ModifyOrderRequest{request}, opts...)
 }
 
-func (e *ExchangeImpl) BulkModify(ctx context.Context, address string, requests []ModifyOrderRequest) *ModifyOrderResponse {
+func (e *ExchangeImpl) BulkModify(ctx context.Context, address string, requests []ModifyOrderRequest, opts ...ActionOption) (*ModifyOrderResponse, error) {
 	var wires []ModifyOrderWire
 	for _, req := range requests {
-		wires = append(wires, ModifyOrderReqToWire(req, e.meta))
+		wire, err := ModifyOrderRequestToWire(req, e.meta)
+		if err != nil {
+			return nil, err
+		}
+		wires = append(wires, wire)
 	}
 
-	timestamp := GetNonce()
 	action := ModifyOrderWiresToModifyOrderAction(wires)
 
-	v, r, s := e.SignL1Action(ctx, address, action, timestamp, (*e.cli).IsMainnet())
-
-	payload := ExchangeRequest{
-		Action:       action,
-		Nonce:        timestamp,
-		Signature:    ToTypedSig(r, s, v),
-		VaultAddress: nil,
+	res, err := e.postL1Action(ctx, address, action, buildActionOptions(opts))
+	if err != nil {
+		return nil, err
 	}
-	res := (*e.cli).Post(ctx, "/exchange", payload)
-	m, _ := json.Marshal(res)
 
-	e.logger.LogInfo(ctx, fmt.Sprintf("response is %s", res))
-
-	response, err := unmarshalModifyOrderResponse(m)
+	response, err := unmarshalModifyOrderResponse(res)
 	if err != nil {
 		e.logger.LogErr(ctx, "failed to unmarshalModifyOrderResponse", err)
-		return nil
+		return nil, &DecodeError{Body: res, Err: err}
+	}
+	if response.ResponseErr != nil {
+		return nil, &ExchangeError{Status: response.Status, Message: *response.ResponseErr}
 	}
 
-	return response
+	return response, nil
 }
 
-func (e *ExchangeImpl) CancelOrder(ctx context.Context, address string, coin string, cloid string) *CancelOrderResponse {
-	info := e.meta[coin]
-	timestamp := GetNonce()
+func (e *ExchangeImpl) CancelOrder(ctx context.Context, address string, coin string, cloid string, opts ...ActionOption) (*CancelOrderResponse, error) {
+	info, ok := e.meta[coin]
+	if !ok {
+		return nil, &UnknownAssetError{Coin: coin}
+	}
 	action := CancelCloidOrderAction{
 		Type: "cancelByCloid",
 		Cancels: []CancelCloidWire{
@@ -344,31 +410,14 @@ func (e *ExchangeImpl) CancelOrder(ctx context.Context, address string, coin str
 		},
 	}
 
-	v, r, s := e.SignL1Action(ctx, address, action, timestamp, (*e.cli).IsMainnet())
-
-	payload := ExchangeRequest{
-		Action:       action,
-		Nonce:        timestamp,
-		Signature:    ToTypedSig(r, s, v),
-		VaultAddress: nil,
-	}
-	res := (*e.cli).Post(ctx, "/exchange", payload)
-	m, _ := json.Marshal(res)
-
-	e.logger.LogInfo(ctx, fmt.Sprintf("response is %s", res))
-
-	response, err := unmarshalCancelOrderResponse(m)
-	if err != nil {
-		e.logger.LogErr(ctx, "failed to unmarshalModifyOrderResponse", err)
-		return nil
-	}
-
-	return response
+	return e.postCancelAction(ctx, address, action, opts)
 }
 
-func (e *ExchangeImpl) CancelOrderByOid(ctx context.Context, address string, coin string, oid int64) *CancelOrderResponse {
-	info := e.meta[coin]
-	timestamp := GetNonce()
+func (e *ExchangeImpl) CancelOrderByOid(ctx context.Context, address string, coin string, oid int64, opts ...ActionOption) (*CancelOrderResponse, error) {
+	info, ok := e.meta[coin]
+	if !ok {
+		return nil, &UnknownAssetError{Coin: coin}
+	}
 	action := CancelOidOrderAction{
 		Type: "cancel",
 		Cancels: []CancelOidWire{
@@ -379,256 +428,499 @@ func (e *ExchangeImpl) CancelOrderByOid(ctx context.Context, address string, coi
 		},
 	}
 
-	v, r, s := e.SignL1Action(ctx, address, action, timestamp, (*e.cli).IsMainnet())
+	return e.postCancelAction(ctx, address, action, opts)
+}
 
-	payload := ExchangeRequest{
-		Action:       action,
-		Nonce:        timestamp,
-		Signature:    ToTypedSig(r, s, v),
-		VaultAddress: nil,
+// BulkCancel cancels orders of any asset, signing one "cancel" action for the requests with an Oid and one
+// "cancelByCloid" action for the others. Results are aligned with requests, if one of the actions fails
+// its requests get the action error and the error is also returned.
+func (e *ExchangeImpl) BulkCancel(ctx context.Context, address string, requests []CancelRequest, opts ...ActionOption) ([]CancelResult, error) {
+	oidAction := CancelOidOrderAction{Type: "cancel"}
+	cloidAction := CancelCloidOrderAction{Type: "cancelByCloid"}
+	var oidIndexes, cloidIndexes []int
+
+	for i, req := range requests {
+		info, ok := e.meta[req.Coin]
+		if !ok {
+			return nil, &UnknownAssetError{Coin: req.Coin}
+		}
+		switch {
+		case req.Oid != nil:
+			oidAction.Cancels = append(oidAction.Cancels, CancelOidWire{Asset: info.AssetId, Oid: *req.Oid})
+			oidIndexes = append(oidIndexes, i)
+		case req.Cloid != nil:
+			cloidAction.Cancels = append(cloidAction.Cancels, CancelCloidWire{Asset: info.AssetId, Cloid: *req.Cloid})
+			cloidIndexes = append(cloidIndexes, i)
+		default:
+			return nil, fmt.Errorf("cancel request %d for %s has neither oid nor cloid", i, req.Coin)
+		}
 	}
 
-	res := (*e.cli).Post(ctx, "/exchange", payload)
-	m, _ := json.Marshal(res)
+	results := make([]CancelResult, len(requests))
+	for i, req := range requests {
+		results[i].Request = req
+	}
+
+	var errs []error
+	if len(oidIndexes) > 0 {
+		response, err := e.postCancelAction(ctx, address, oidAction, opts)
+		errs = append(errs, setCancelResults(results, oidIndexes, response, err))
+	}
+	if len(cloidIndexes) > 0 {
+		response, err := e.postCancelAction(ctx, address, cloidAction, opts)
+		errs = append(errs, setCancelResults(results, cloidIndexes, response, err))
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// setCancelResults stores the statuses of a cancel action in the results of the requests it was built from
+func setCancelResults(results []CancelResult, indexes []int, response *CancelOrderResponse, err error) error {
+	if err == nil && len(response.Response.Data.Statuses) != len(indexes) {
+		err = fmt.Errorf("expected %d cancel statuses, got %d", len(indexes), len(response.Response.Data.Statuses))
+	}
+	for j, i := range indexes {
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		status := response.Response.Data.Statuses[j]
+		if !status.Success {
+			results[i].Err = &OrderError{Message: *status.Error}
+		}
+	}
+	return err
+}
 
-	e.logger.LogInfo(ctx, fmt.Sprintf("response is %s", res))
+// MaxCancelsPerAction is the number of orders CancelAll cancels in each request
+const MaxCancelsPerAction = 100
 
-	response, err := unmarshalCancelOrderResponse(m)
+// CancelAll cancels every open order of the account, or only those of the coins in coinFilter when it isn't empty
+func (e *ExchangeImpl) CancelAll(ctx context.Context, address string, coinFilter []string, opts ...ActionOption) ([]CancelResult, error) {
+	orders, err := e.infoApi.FindOpenOrders(ctx, buildActionOptions(opts).account(address))
 	if err != nil {
-		e.logger.LogErr(ctx, "failed to unmarshalModifyOrderResponse", err)
-		return nil
+		return nil, err
+	}
+
+	coins := make(map[string]bool, len(coinFilter))
+	for _, coin := range coinFilter {
+		coins[coin] = true
 	}
 
-	return response
+	var requests []CancelRequest
+	for _, order := range orders {
+		if len(coins) > 0 && !coins[order.Coin] {
+			continue
+		}
+		oid := order.Oid
+		requests = append(requests, CancelRequest{Coin: order.Coin, Oid: &oid})
+	}
+
+	var results []CancelResult
+	var errs []error
+	for start := 0; start < len(requests); start += MaxCancelsPerAction {
+		end := start + MaxCancelsPerAction
+		if end > len(requests) {
+			end = len(requests)
+		}
+		batch, err := e.BulkCancel(ctx, address, requests[start:end], opts...)
+		if batch == nil {
+			return results, err
+		}
+		results = append(results, batch...)
+		errs = append(errs, err)
+	}
+
+	return results, errors.Join(errs...)
 }
 
-func (e *ExchangeImpl) UpdateLeverage(context context.Context, request UpdateLeverageRequest) any {
+func (e *ExchangeImpl) postCancelAction(ctx context.Context, address string, action any, opts []ActionOption) (*CancelOrderResponse, error) {
+	res, err := e.postL1Action(ctx, address, action, buildActionOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := unmarshalCancelOrderResponse(res)
+	if err != nil {
+		e.logger.LogErr(ctx, "failed to unmarshalCancelOrderResponse", err)
+		return nil, &DecodeError{Body: res, Err: err}
+	}
+	if response.ResponseErr != nil {
+		return nil, &ExchangeError{Status: response.Status, Message: *response.ResponseErr}
+	}
+
+	return response, nil
+}
 
-	timestamp := GetNonce()
+func (e *ExchangeImpl) UpdateLeverage(context context.Context, request UpdateLeverageRequest, opts ...ActionOption) (*ActionResponse, error) {
+
+	info, ok := e.meta[request.Coin]
+	if !ok {
+		return nil, &UnknownAssetError{Coin: request.Coin}
+	}
 
 	action := UpdateLeverageAction{
 		Type:     "updateLeverage",
-		Asset:    e.meta[request.Coin].AssetId,
+		Asset:    info.AssetId,
 		IsCross:  request.IsCross,
 		Leverage: request.Leverage,
 	}
 
-	v, r, s := e.SignL1Action(context, request.Address, action, timestamp, (*e.cli).IsMainnet())
-
-	payload := ExchangeRequest{
-		Action:       action,
-		Nonce:        timestamp,
-		Signature:    ToTypedSig(r, s, v),
-		VaultAddress: nil,
+	res, err := e.postL1Action(context, request.Address, action, buildActionOptions(opts))
+	if err != nil {
+		return nil, err
 	}
 
-	res := (*e.cli).Post(context, "/exchange", payload)
-	return res
+	return e.parseActionResponse(context, res)
 }
 
-func (e *ExchangeImpl) FindOrder(context context.Context, address string, cloid string) OrderResponse {
-	return e.infoApi.FindOrder(context, address, cloid)
-}
+// UpdateIsolatedMargin adds usdDelta of margin to an isolated position, or removes it when usdDelta is negative
+func (e *ExchangeImpl) UpdateIsolatedMargin(ctx context.Context, address string, coin string, isBuy bool, usdDelta float64, opts ...ActionOption) (*ActionResponse, error) {
+	info, ok := e.meta[coin]
+	if !ok {
+		return nil, &UnknownAssetError{Coin: coin}
+	}
 
-func (e *ExchangeImpl) GetUserFills(context context.Context, address string) []OrderFill {
-	return e.infoApi.GetUserFills(context, address)
+	action := UpdateIsolatedMarginAction{
+		Type:  "updateIsolatedMargin",
+		Asset: info.AssetId,
+		IsBuy: isBuy,
+		Ntli:  int64(math.Round(usdDelta * 1e6)),
+	}
+
+	res, err := e.postL1Action(ctx, address, action, buildActionOptions(opts))
+	if err != nil {
+		return nil, err
+	}
 
+	return e.parseActionResponse(ctx, res)
 }
 
-func (e *ExchangeImpl) Withdraw(context context.Context, request WithdrawRequest) *WithdrawResponse {
+// TargetLiquidationPx adds or removes margin from an isolated position so its liquidation price moves to liquidationPx
+func (e *ExchangeImpl) TargetLiquidationPx(ctx context.Context, address string, coin string, liquidationPx float64, opts ...ActionOption) (*ActionResponse, error) {
+	options := buildActionOptions(opts)
+	state, err := e.infoApi.GetUserState(ctx, options.account(address))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, position := range state.AssetPositions {
+		item := position.Position
+		if !strings.EqualFold(coin, item.Coin) {
+			continue
+		}
 
-	timestamp := GetNonce()
-	chain := "Testnet"
-	chainId := "0x66eee"
+		delta, err := MarginDeltaForLiquidationPx(item, liquidationPx)
+		if err != nil {
+			return nil, err
+		}
+		szi, _ := strconv.ParseFloat(item.Szi, 64)
 
-	if (*e.cli).IsMainnet() {
-		chain = "Mainnet"
-		chainId = "0xa4b1"
+		return e.UpdateIsolatedMargin(ctx, address, item.Coin, szi > 0, delta, opts...)
 	}
 
-	amount := ConvertTo2Decimals(request.Amount)
-	szDecimals := 2
+	return nil, fmt.Errorf("%w for asset %s", ErrNoPosition, coin)
+}
 
-	action := WithdrawAction{
-		Type:             "withdraw3",
-		HLChain:          chain,
-		SignatureChainId: chainId,
-		Amount:           SizeToWire(amount, szDecimals),
-		Destination:      request.Destination,
-		Time:             timestamp,
+// TwapOrder starts a TWAP which the exchange executes as sz split in slices over the given minutes,
+// randomize spreads the slices at random intervals instead of every 30 seconds
+func (e *ExchangeImpl) TwapOrder(ctx context.Context, address string, coin string, isBuy bool, sz float64, minutes int, randomize bool, reduceOnly bool, opts ...ActionOption) (*TwapOrderResponse, error) {
+	info, ok := e.meta[coin]
+	if !ok {
+		return nil, &UnknownAssetError{Coin: coin}
 	}
 
-	v, r, s := e.SignWithdrawAction(context, request.Address, action, (*e.cli).IsMainnet())
+	action := TwapOrderAction{
+		Type: "twapOrder",
+		Twap: TwapWire{
+			Asset:      info.AssetId,
+			IsBuy:      isBuy,
+			Size:       SizeToWire(sz, info.SzDecimals),
+			ReduceOnly: reduceOnly,
+			Minutes:    minutes,
+			Randomize:  randomize,
+		},
+	}
 
-	payload := ExchangeRequest{
-		Action:       action,
-		Nonce:        timestamp,
-		Signature:    ToTypedSig(r, s, v),
-		VaultAddress: nil,
+	res, err := e.postL1Action(ctx, address, action, buildActionOptions(opts))
+	if err != nil {
+		return nil, err
 	}
 
-	res := (*e.cli).Post(context, "/exchange", payload)
-	e.logger.LogInfo(context, fmt.Sprintf("Response is %s", res))
-	m, _ := json.Marshal(res)
-	response := &WithdrawResponse{}
-	_ = json.Unmarshal(m, &response)
-	response.Nonce = timestamp
+	response, err := unmarshalTwapOrderResponse(res)
+	if err != nil {
+		e.logger.LogErr(ctx, "failed to unmarshalTwapOrderResponse", err)
+		return nil, &DecodeError{Body: res, Err: err}
+	}
+	if response.ResponseErr != nil {
+		return nil, &ExchangeError{Status: response.Status, Message: *response.ResponseErr}
+	}
 
-	return response
+	return response, nil
 }
 
-var lastNonce *int64
-var lastNonceMu sync.Mutex
+func (e *ExchangeImpl) TwapCancel(ctx context.Context, address string, coin string, twapId int64, opts ...ActionOption) (*TwapCancelResponse, error) {
+	info, ok := e.meta[coin]
+	if !ok {
+		return nil, &UnknownAssetError{Coin: coin}
+	}
 
-// GetNonce is thread safe and makes sure that all nonces are increasing, even if called in the same millisecond
-func GetNonce() int64 {
-	lastNonceMu.Lock()
-	defer lastNonceMu.Unlock()
-
-	nonce := time.Now().UnixMilli()
-	if lastNonce == nil {
-		lastNonce = &nonce
-		return nonce
-	} else if *lastNonce >= nonce {
-		*lastNonce += 1
-		return *lastNonce
-	} else {
-		lastNonce = &nonce
-		return nonce
+	action := TwapCancelAction{
+		Type:   "twapCancel",
+		Asset:  info.AssetId,
+		TwapId: twapId,
 	}
-}
 
-func (e *ExchangeImpl) SignL1Action(ctx context.Context, address string, action any, timestamp int64, isMainnet bool) (byte, [32]byte, [32]byte) {
-	hash := e.buildActionHash(ctx, action, "", timestamp)
-	message := buildMessage(hash.Bytes(), isMainnet)
-	return e.SignInner(ctx, address, message, isMainnet)
+	res, err := e.postL1Action(ctx, address, action, buildActionOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := unmarshalTwapCancelResponse(res)
+	if err != nil {
+		e.logger.LogErr(ctx, "failed to unmarshalTwapCancelResponse", err)
+		return nil, &DecodeError{Body: res, Err: err}
+	}
+	if response.ResponseErr != nil {
+		return nil, &ExchangeError{Status: response.Status, Message: *response.ResponseErr}
+	}
+
+	return response, nil
 }
 
-func (e *ExchangeImpl) SignInner(ctx context.Context, address string, message apitypes.TypedDataMessage, isMainNet bool) (byte, [32]byte, [32]byte) {
+// MinScheduleCancelDelay is how far in the future a scheduled cancel must be
+const MinScheduleCancelDelay = 5 * time.Second
 
-	signer := NewSigner(e.keyManager)
-	req := SigRequest{
-		PrimaryType: "Agent",
-		DType: []apitypes.Type{
-			{
-				Name: "source",
-				Type: "string",
-			},
-			{
-				Name: "connectionId",
-				Type: "bytes32",
-			},
-		},
-		DTypeMsg:  message,
-		IsMainNet: isMainNet,
+// ScheduleCancel cancels all open orders of the account at the given time, which must be at least
+// MinScheduleCancelDelay in the future. A nil time removes the scheduled cancel. The exchange only allows a few triggered cancels per day.
+func (e *ExchangeImpl) ScheduleCancel(ctx context.Context, address string, at *time.Time, opts ...ActionOption) (*ActionResponse, error) {
+	action := ScheduleCancelAction{Type: "scheduleCancel"}
+	if at != nil {
+		ms := at.UnixMilli()
+		action.Time = &ms
+	}
+
+	res, err := e.postL1Action(ctx, address, action, buildActionOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return e.parseActionResponse(ctx, res)
+}
+
+// ScheduleCancelHeartbeat works as a dead man's switch, scheduling a cancel timeout from now and pushing it
+// forward every interval until ctx is done, so the open orders are cancelled if the process stops.
+// Failed refreshes are logged and retried on the next tick, the scheduled cancel is kept when ctx is done.
+func (e *ExchangeImpl) ScheduleCancelHeartbeat(ctx context.Context, address string, timeout time.Duration, interval time.Duration, opts ...ActionOption) error {
+	if timeout < MinScheduleCancelDelay {
+		return fmt.Errorf("heartbeat timeout %s is shorter than %s", timeout, MinScheduleCancelDelay)
+	}
+	if interval <= 0 || interval >= timeout {
+		return fmt.Errorf("heartbeat interval %s must be positive and shorter than timeout %s", interval, timeout)
 	}
 
-	v, r, s, err := signer.Sign(address, req)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		at := time.Now().Add(timeout)
+		if _, err := e.ScheduleCancel(ctx, address, &at, opts...); err != nil {
+			e.logger.LogErr(ctx, "failed to refresh scheduled cancel", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
 
+// postL1Action signs the action with a fresh nonce and posts it to the exchange
+func (e *ExchangeImpl) postL1Action(ctx context.Context, address string, action any, options actionOptions) (json.RawMessage, error) {
+	timestamp, err := e.nonceFor(e.agentOf(address))
 	if err != nil {
-		e.logger.LogErr(ctx, "Failed to sign request", err)
-		panic("Failed to sign request")
+		return nil, err
 	}
 
-	return v, r, s
+	return e.postL1ActionWithNonce(ctx, address, action, options, timestamp)
+}
 
+func (e *ExchangeImpl) postL1ActionWithNonce(ctx context.Context, address string, action any, options actionOptions, timestamp int64) (json.RawMessage, error) {
+	return e.postL1ActionAs(ctx, e.agentOf(address), action, options, timestamp)
 }
 
-func (e *ExchangeImpl) SignPointsAction(ctx context.Context, address string, timestamp int64, mainnet bool) (byte, [32]byte, [32]byte) {
+// postL1ActionAs signs the action with the key of signer itself, not the one of its agent
+func (e *ExchangeImpl) postL1ActionAs(ctx context.Context, signer string, action any, options actionOptions, timestamp int64) (json.RawMessage, error) {
+	expiresAfter := e.expiresAfter(options)
+	v, r, s, err := e.signL1Action(ctx, signer, action, options.vault(), timestamp, expiresAfter, (*e.cli).IsMainnet())
+	if err != nil {
+		return nil, err
+	}
 
-	message := apitypes.TypedDataMessage{
-		"hyperliquidChain": "Mainnet",
-		"time":             strconv.FormatInt(timestamp, 10),
+	payload := ExchangeRequest{
+		Action:       action,
+		Nonce:        timestamp,
+		Signature:    ToTypedSig(r, s, v),
+		VaultAddress: options.vaultAddress,
+		ExpiresAfter: expiresAfter,
 	}
 
-	signer := NewSigner(e.keyManager)
-	req := SigRequest{
-		PrimaryType: "Hyperliquid:UserPoints",
-		DType: []apitypes.Type{
-			{
-				Name: "hyperliquidChain",
-				Type: "string",
-			},
-			{
-				Name: "time",
-				Type: "uint64",
-			},
-		},
-		DTypeMsg:  message,
-		IsMainNet: mainnet,
+	res, err := (*e.cli).Post(ctx, "/exchange", payload)
+	if err != nil {
+		return nil, err
 	}
 
-	v, r, s, err := signer.Sign(address, req)
+	e.logger.LogInfo(ctx, fmt.Sprintf("Response is %s", res))
+
+	return res, nil
+}
 
+func (e *ExchangeImpl) parseActionResponse(ctx context.Context, res json.RawMessage) (*ActionResponse, error) {
+	response, err := unmarshalActionResponse(res)
 	if err != nil {
-		e.logger.LogErr(ctx, "Failed to sign request", err)
-		panic("Failed to sign request")
+		e.logger.LogErr(ctx, "failed to unmarshalActionResponse", err)
+		return nil, &DecodeError{Body: res, Err: err}
+	}
+	if response.ResponseErr != nil {
+		return nil, &ExchangeError{Status: response.Status, Message: *response.ResponseErr}
 	}
+	return response, nil
+}
+
+func (e *ExchangeImpl) FindOrder(context context.Context, address string, cloid string) (OrderResponse, error) {
+	return e.infoApi.FindOrder(context, address, cloid)
+}
 
-	return v, r, s
+func (e *ExchangeImpl) GetUserFills(context context.Context, address string) ([]OrderFill, error) {
+	return e.infoApi.GetUserFills(context, address)
 
 }
 
-func (e *ExchangeImpl) SignWithdrawAction(ctx context.Context, address string, action WithdrawAction, mainnet bool) (byte, [32]byte, [32]byte) {
+func (e *ExchangeImpl) Withdraw(context context.Context, request WithdrawRequest) (*WithdrawResponse, error) {
 
-	message := apitypes.TypedDataMessage{
-		"hyperliquidChain": action.HLChain,
-		"destination":      action.Destination,
-		"amount":           action.Amount,
-		"time":             strconv.FormatInt(action.Time, 10),
+	timestamp, err := e.nonceFor(request.Address)
+	if err != nil {
+		return nil, err
 	}
 
-	signer := NewSigner(e.keyManager)
-	req := SigRequest{
-		PrimaryType: "HyperliquidTransaction:Withdraw",
+	amount := ConvertTo2Decimals(request.Amount)
+	szDecimals := 2
+
+	action := WithdrawAction{
+		Type:        "withdraw3",
+		Amount:      SizeToWire(amount, szDecimals),
+		Destination: request.Destination,
+		Time:        timestamp,
+	}
+
+	return e.postUserSignedAction(context, request.Address, action, timestamp)
+}
+
+// GetNonce is thread safe and makes sure that all nonces are increasing, even if called in the same millisecond.
+//
+// Deprecated: the nonces of GetNonce are not ordered with the ones exchanges take for each signer, so a request
+// built with it can reuse the nonce of an action just sent by the same signer. Use NonceSource.Next(signer)
+// with the source of the exchange instead.
+func GetNonce() int64 {
+	nonce, _ := defaultNonceSource.Next("")
+	return nonce
+}
+
+// expiresAfter returns the expiry of an L1 action, set per call or from the exchange default
+func (e *ExchangeImpl) expiresAfter(options actionOptions) *int64 {
+	if options.expiresAfter != nil {
+		return options.expiresAfter
+	}
+	if e.expiry > 0 {
+		ms := time.Now().Add(e.expiry).UnixMilli()
+		return &ms
+	}
+	return nil
+}
+
+// nonceFor returns the nonce of an action signed by signer, which is the agent of the address for L1 actions
+func (e *ExchangeImpl) nonceFor(signer string) (int64, error) {
+	return e.nonces.Next(signer)
+}
+
+// SignL1Action signs an action with the key of address. vaultAddress is empty unless trading for a vault or sub-account,
+// expiresAfter is nil unless the action must be rejected after that time in milliseconds.
+func (e *ExchangeImpl) SignL1Action(ctx context.Context, address string, action any, vaultAddress string, timestamp int64, expiresAfter *int64, isMainnet bool) (byte, [32]byte, [32]byte, error) {
+	return e.signL1Action(ctx, e.agentOf(address), action, vaultAddress, timestamp, expiresAfter, isMainnet)
+}
+
+func (e *ExchangeImpl) signL1Action(ctx context.Context, signer string, action any, vaultAddress string, timestamp int64, expiresAfter *int64, isMainnet bool) (byte, [32]byte, [32]byte, error) {
+	hash, err := e.buildActionHash(ctx, action, vaultAddress, timestamp, expiresAfter)
+	if err != nil {
+		return 0, [32]byte{}, [32]byte{}, &SigningError{Address: signer, Err: err}
+	}
+	message := buildMessage(hash.Bytes(), isMainnet)
+	return e.SignInner(ctx, signer, message, isMainnet)
+}
+
+// agentSigRequest is the request signing the hash of an L1 action
+func agentSigRequest(message apitypes.TypedDataMessage, isMainNet bool) SigRequest {
+	return SigRequest{
+		PrimaryType: "Agent",
 		DType: []apitypes.Type{
 			{
-				Name: "hyperliquidChain",
-				Type: "string",
-			},
-			{
-				Name: "destination",
-				Type: "string",
-			},
-			{
-				Name: "amount",
+				Name: "source",
 				Type: "string",
 			},
 			{
-				Name: "time",
-				Type: "uint64",
+				Name: "connectionId",
+				Type: "bytes32",
 			},
 		},
 		DTypeMsg:  message,
-		IsMainNet: mainnet,
+		IsMainNet: isMainNet,
 	}
+}
 
-	v, r, s, err := signer.Sign(address, req)
+func (e *ExchangeImpl) SignInner(ctx context.Context, address string, message apitypes.TypedDataMessage, isMainNet bool) (byte, [32]byte, [32]byte, error) {
+
+	signer := NewSigner(e.keyManager)
+	v, r, s, err := signer.Sign(address, agentSigRequest(message, isMainNet))
 
 	if err != nil {
 		e.logger.LogErr(ctx, "Failed to sign request", err)
-		panic("Failed to sign request")
+		return 0, [32]byte{}, [32]byte{}, &SigningError{Address: address, Err: err}
 	}
 
-	return v, r, s
+	return v, r, s, nil
 
 }
 
-func (e *ExchangeImpl) buildActionHash(ctx context.Context, action any, vaultAd string, nonce int64) common.Hash {
-	var (
-		data []byte
-	)
+func (e *ExchangeImpl) SignPointsAction(ctx context.Context, address string, timestamp int64, mainnet bool) (byte, [32]byte, [32]byte, error) {
+	chain, _ := UserSignedChain(mainnet)
+	action := map[string]any{
+		"type":             "userPoints2",
+		"hyperliquidChain": chain,
+		"time":             timestamp,
+	}
+	return e.signUserSignedAction(ctx, address, action, mainnet)
+}
 
+func (e *ExchangeImpl) SignWithdrawAction(ctx context.Context, address string, action WithdrawAction, mainnet bool) (byte, [32]byte, [32]byte, error) {
+	return e.signUserSignedAction(ctx, address, action, mainnet)
+}
+
+func (e *ExchangeImpl) buildActionHash(ctx context.Context, action any, vaultAd string, nonce int64, expiresAfter *int64) (common.Hash, error) {
 	var buf bytes.Buffer
 	enc := msgpack.NewEncoder(&buf)
 	enc.UseCompactInts(true)
 	err := enc.Encode(action)
 	if err != nil {
 		e.logger.LogErr(ctx, "Failed to pack the data", err)
-		panic(fmt.Sprintf("Failed to pack the data %s", err))
+		return common.Hash{}, fmt.Errorf("failed to pack the data: %w", err)
 	}
-	data = buf.Bytes()
 
+	return hashPackedAction(buf.Bytes(), vaultAd, nonce, expiresAfter), nil
+}
+
+// hashPackedAction hashes an action packed with msgpack together with the nonce, vault and expiry of the request
+func hashPackedAction(data []byte, vaultAd string, nonce int64, expiresAfter *int64) common.Hash {
 	nonceBytes := make([]byte, 8)
 	binary.BigEndian.PutUint64(nonceBytes, uint64(nonce))
 	data = ArrayAppend(data, nonceBytes)
@@ -640,8 +932,14 @@ func (e *ExchangeImpl) buildActionHash(ctx context.Context, action any, vaultAd
 		data = ArrayAppend(data, HexToBytes(vaultAd))
 	}
 
-	result := crypto.Keccak256Hash(data)
-	return result
+	if expiresAfter != nil {
+		expiresBytes := make([]byte, 8)
+		binary.BigEndian.PutUint64(expiresBytes, uint64(*expiresAfter))
+		data = ArrayAppend(data, []byte("\x00"))
+		data = ArrayAppend(data, expiresBytes)
+	}
+
+	return crypto.Keccak256Hash(data)
 }
 
 func buildMessage(hash []byte, isMain bool) apitypes.TypedDataMessage {
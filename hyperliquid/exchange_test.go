@@ -1,29 +1,56 @@
 package hyperliquid
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/mfgmateus/hyperliquid-go-sdk/v2/cryptoutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/mfgmateus/hyperliquid-go-sdk/v3/cryptoutil"
 	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 const Address = "0x60Cc17b782e9c5f14806663f8F617921275b9720"
 const PrivateKey = "35e02d3d3e6f65dcc37886ab779af1c4e01d4b915a06bdacbcdb4da09497996c"
 
 var (
-	logger      = &DefaultLogger{}
-	keyManager  = NewKeyManager(PrivateKey)
-	baseClient  = NewApiDefault(TestnetUrl, logger)
-	exchangeApi = NewExchange(&baseClient, &keyManager, logger)
-	infoApi     = NewInfoApi(&baseClient)
+	logger     = &DefaultLogger{}
+	keyManager = NewKeyManager(PrivateKey)
+	baseClient = NewApiDefault(TestnetUrl, logger)
+	infoApi    = NewInfoApi(&baseClient)
 )
 
+var (
+	testnetOnce     sync.Once
+	testnetExchange ExchangeApi
+	testnetErr      error
+)
+
+// newTestnetExchange returns the exchange used by the integration tests, which are skipped when it can't be built,
+// e.g. when the testnet can't be reached
+func newTestnetExchange(t *testing.T) ExchangeApi {
+	testnetOnce.Do(func() {
+		testnetExchange, testnetErr = NewExchange(&baseClient, &keyManager, logger)
+	})
+	if testnetErr != nil {
+		t.Skipf("testnet exchange unavailable: %s", testnetErr)
+	}
+	return testnetExchange
+}
+
 type SingleKeyManager struct {
 	privKey *ecdsa.PrivateKey
 }
@@ -39,6 +66,7 @@ func NewKeyManager(privKey string) KeyManager {
 }
 
 func TestMarketOpenAndClose(t *testing.T) {
+	exchangeApi := newTestnetExchange(t)
 
 	size := 7000.0
 	cloid := GetRandomCloid()
@@ -52,11 +80,13 @@ func TestMarketOpenAndClose(t *testing.T) {
 		IsBuy:   true,
 	}
 
-	result := exchangeApi.MarketOpen(context.Background(), req)
+	result, err := exchangeApi.MarketOpen(context.Background(), req)
+	require.NoError(t, err)
 	m, _ := json.Marshal(result)
 	fmt.Printf("Open Result is %s\n", m)
 
-	r2 := exchangeApi.FindOrder(context.Background(), Address, cloid)
+	r2, err := exchangeApi.FindOrder(context.Background(), Address, cloid)
+	require.NoError(t, err)
 	m, _ = json.Marshal(r2)
 	fmt.Printf("Result is %s\n", m)
 
@@ -71,7 +101,8 @@ func TestMarketOpenAndClose(t *testing.T) {
 	////wait for 2 seconds?
 	time.Sleep(time.Duration(time.Duration.Seconds(2)))
 
-	result = exchangeApi.MarketClose(context.Background(), closeReq)
+	result, err = exchangeApi.MarketClose(context.Background(), closeReq)
+	require.NoError(t, err)
 	fmt.Printf("%s\n", *result.GetAvgPrice())
 	m, _ = json.Marshal(result)
 
@@ -79,6 +110,7 @@ func TestMarketOpenAndClose(t *testing.T) {
 }
 
 func TestMarketClose(t *testing.T) {
+	exchangeApi := newTestnetExchange(t)
 
 	cloid := GetRandomCloid()
 
@@ -87,20 +119,25 @@ func TestMarketClose(t *testing.T) {
 		Cloid: &cloid,
 	}
 
-	result := exchangeApi.MarketClose(context.Background(), req)
+	result, err := exchangeApi.MarketClose(context.Background(), req)
+	require.NoError(t, err)
 	fmt.Printf("%s\n", *result.GetAvgPrice())
 	m, _ := json.Marshal(result)
 	fmt.Printf("Result is %s\n", m)
 
-	r2 := exchangeApi.FindOrder(context.Background(), Address, cloid)
+	r2, err := exchangeApi.FindOrder(context.Background(), Address, cloid)
+	require.NoError(t, err)
 	m, _ = json.Marshal(r2)
 	fmt.Printf("Result is %s", m)
 
 }
 
 func TestAccountInfo(t *testing.T) {
+	// only for the skip when the testnet can't be reached
+	newTestnetExchange(t)
 
-	state := infoApi.GetUserState(context.Background(), Address)
+	state, err := infoApi.GetUserState(context.Background(), Address)
+	require.NoError(t, err)
 
 	m, _ := json.Marshal(state)
 	fmt.Printf("Result is %s\n", m)
@@ -114,6 +151,7 @@ func TestAccountInfo(t *testing.T) {
 }
 
 func TestUpdateLeverage(t *testing.T) {
+	exchangeApi := newTestnetExchange(t)
 
 	req := UpdateLeverageRequest{
 		Coin:     "ARB",
@@ -121,7 +159,8 @@ func TestUpdateLeverage(t *testing.T) {
 		IsCross:  false,
 	}
 
-	result := exchangeApi.UpdateLeverage(context.Background(), req)
+	result, err := exchangeApi.UpdateLeverage(context.Background(), req)
+	require.NoError(t, err)
 	m, _ := json.Marshal(result)
 
 	fmt.Printf("Result is %s\n", m)
@@ -129,8 +168,10 @@ func TestUpdateLeverage(t *testing.T) {
 }
 
 func TestGetUserFills(t *testing.T) {
+	exchangeApi := newTestnetExchange(t)
 
-	fills := exchangeApi.GetUserFills(context.Background(), Address)
+	fills, err := exchangeApi.GetUserFills(context.Background(), Address)
+	require.NoError(t, err)
 	m, _ := json.Marshal(fills)
 	fmt.Printf("Result is %s\n", m)
 
@@ -140,6 +181,7 @@ func TestGetUserFills(t *testing.T) {
 }
 
 func TestTrigger(t *testing.T) {
+	exchangeApi := newTestnetExchange(t)
 
 	triggerPrice := 3090.10
 	decimals := 4
@@ -160,19 +202,23 @@ func TestTrigger(t *testing.T) {
 		Cloid: &cloid,
 	}
 
-	result := exchangeApi.Trigger(context.Background(), req)
+	result, err := exchangeApi.Trigger(context.Background(), req)
+	require.NoError(t, err)
 	m, _ := json.Marshal(result)
 	fmt.Printf("Trigger Result is %s\n", m)
 
-	r2 := exchangeApi.FindOrder(context.Background(), Address, cloid)
+	r2, err := exchangeApi.FindOrder(context.Background(), Address, cloid)
+	require.NoError(t, err)
 	m, _ = json.Marshal(r2)
 	fmt.Printf("Result is %s", m)
 
 }
 
 func TestWithdraw(t *testing.T) {
+	exchangeApi := newTestnetExchange(t)
 
-	state := infoApi.GetUserState(context.Background(), Address)
+	state, err := infoApi.GetUserState(context.Background(), Address)
+	require.NoError(t, err)
 
 	m, _ := json.Marshal(state)
 	fmt.Printf("Result is %s\n", m)
@@ -183,7 +229,8 @@ func TestWithdraw(t *testing.T) {
 		Amount:      2,
 	}
 
-	res := exchangeApi.Withdraw(context.Background(), req)
+	res, err := exchangeApi.Withdraw(context.Background(), req)
+	require.NoError(t, err)
 	m, _ = json.Marshal(res)
 	fmt.Printf("Result is %s\n", m)
 
@@ -258,6 +305,7 @@ func TestCancel(t *testing.T) {
 }
 
 func TestEditOrder(t *testing.T) {
+	exchangeApi := newTestnetExchange(t)
 	ctx := context.Background()
 	coin := "KPEPE"
 
@@ -266,7 +314,7 @@ func TestEditOrder(t *testing.T) {
 	cloid3 := GetRandomCloid()
 
 	// create order
-	exchangeApi.Order(ctx, Address, OrderRequest{
+	_, err := exchangeApi.Order(ctx, Address, OrderRequest{
 		Coin:       coin,
 		IsBuy:      true,
 		Sz:         3000,
@@ -275,8 +323,10 @@ func TestEditOrder(t *testing.T) {
 		ReduceOnly: false,
 		Cloid:      &cloid1,
 	}, GroupingNa)
+	require.NoError(t, err)
 
-	order := exchangeApi.FindOrder(ctx, Address, cloid1)
+	order, err := exchangeApi.FindOrder(ctx, Address, cloid1)
+	require.NoError(t, err)
 	require.Equal(t, "order", order.Status)
 	require.Equal(t, "open", order.Order.Status)
 	require.Equal(t, "3000.0", order.Order.Order.Sz)
@@ -286,7 +336,7 @@ func TestEditOrder(t *testing.T) {
 	logger.LogInfo(ctx, fmt.Sprintf("initial order: %s", j))
 
 	// modify order by Cloid
-	exchangeApi.ModifyOrder(ctx, Address, ModifyOrderRequest{
+	_, err = exchangeApi.ModifyOrder(ctx, Address, ModifyOrderRequest{
 		OidOrCloid: cloid1,
 		Coin:       coin,
 		IsBuy:      true,
@@ -296,9 +346,11 @@ func TestEditOrder(t *testing.T) {
 		ReduceOnly: false,
 		Cloid:      &cloid2,
 	})
+	require.NoError(t, err)
 
 	// new order was placed
-	order = exchangeApi.FindOrder(ctx, Address, cloid2)
+	order, err = exchangeApi.FindOrder(ctx, Address, cloid2)
+	require.NoError(t, err)
 	require.Equal(t, "order", order.Status)
 	require.Equal(t, "open", order.Order.Status)
 	require.Equal(t, "3005.0", order.Order.Order.Sz)
@@ -308,12 +360,13 @@ func TestEditOrder(t *testing.T) {
 	logger.LogInfo(ctx, fmt.Sprintf("modified once order: %s", j))
 
 	// original order is canceled
-	originalOrder := exchangeApi.FindOrder(ctx, Address, cloid1)
+	originalOrder, err := exchangeApi.FindOrder(ctx, Address, cloid1)
+	require.NoError(t, err)
 	require.Equal(t, "order", originalOrder.Status)
 	require.Equal(t, "canceled", originalOrder.Order.Status)
 
 	// modify order by Cloid
-	exchangeApi.ModifyOrder(ctx, Address, ModifyOrderRequest{
+	_, err = exchangeApi.ModifyOrder(ctx, Address, ModifyOrderRequest{
 		OidOrCloid: order.Order.Order.Oid,
 		Coin:       coin,
 		IsBuy:      true,
@@ -323,8 +376,10 @@ func TestEditOrder(t *testing.T) {
 		ReduceOnly: false,
 		Cloid:      &cloid3,
 	})
+	require.NoError(t, err)
 
-	order = exchangeApi.FindOrder(ctx, Address, cloid3)
+	order, err = exchangeApi.FindOrder(ctx, Address, cloid3)
+	require.NoError(t, err)
 	require.Equal(t, "order", order.Status)
 	require.Equal(t, "open", order.Order.Status)
 	require.Equal(t, "3007.0", order.Order.Order.Sz)
@@ -335,10 +390,11 @@ func TestEditOrder(t *testing.T) {
 }
 
 func Test_CreateOrder_SizeZero(t *testing.T) {
+	exchangeApi := newTestnetExchange(t)
 	ctx := context.Background()
 	coin := "KPEPE"
 
-	response := exchangeApi.Order(ctx, Address, OrderRequest{
+	response, err := exchangeApi.Order(ctx, Address, OrderRequest{
 		Coin:       coin,
 		IsBuy:      true,
 		Sz:         0,
@@ -347,6 +403,7 @@ func Test_CreateOrder_SizeZero(t *testing.T) {
 		ReduceOnly: false,
 		Cloid:      nil,
 	}, GroupingNa)
+	require.NoError(t, err)
 
 	require.Equal(t, 1, len(response.Response.Data.Statuses))
 	require.Equal(t, "Order has zero size.", *response.Response.Data.Statuses[0].Error)
@@ -354,9 +411,10 @@ func Test_CreateOrder_SizeZero(t *testing.T) {
 
 func Test_CreateOrder_OuterError(t *testing.T) {
 	ctx := context.Background()
+	mock := &mockApi{response: `{"status":"err","response":"L1 error: User or API Wallet 0x1c4ee0ea5d29fe5eb6d40d9c6f9b3f1b5ff1a6b1 does not exist."}`}
 
 	// send a payload manually, which has an invalid signature
-	// this should trigger a specific error, like  "User or API Wallet 0xXXXX does not exist"
+	// the exchange answers with a specific error, like  "User or API Wallet 0xXXXX does not exist"
 	// this error is in the response field, but it's a string
 	// this tests the unmarshalPlaceOrderResponse method, which should set ResponseErr accordingly
 	payload := ExchangeRequest{
@@ -374,17 +432,17 @@ func Test_CreateOrder_OuterError(t *testing.T) {
 				},
 			},
 		},
-		Nonce: GetNonce(),
+		Nonce: 1700000000000,
 		Signature: RsvSignature{
 			R: "0xa9a7cf2b26c9fa22b8e943f8bec93dd091b10c9d2f32e9bd98b70edaec9b908e",
 			S: "0x1e2ba41a0a32e1ac23a9e63ede05afd06994f1e269b381d9edfb13c9dd4485ee",
 			V: 27,
 		},
 	}
-	response := baseClient.Post(ctx, "/exchange", payload)
+	response, err := mock.Post(ctx, "/exchange", payload)
+	require.NoError(t, err)
 
-	m, _ := json.Marshal(response)
-	placeOrderResponse, err := unmarshalPlaceOrderResponse(m)
+	placeOrderResponse, err := unmarshalPlaceOrderResponse(response)
 	require.NoError(t, err)
 	require.Nil(t, placeOrderResponse.Response)
 	require.Contains(t, *placeOrderResponse.ResponseErr, "L1 error: User or API Wallet")
@@ -392,9 +450,10 @@ func Test_CreateOrder_OuterError(t *testing.T) {
 
 func Test_Cancel_OuterError(t *testing.T) {
 	ctx := context.Background()
+	mock := &mockApi{response: `{"status":"err","response":"L1 error: User or API Wallet 0x1c4ee0ea5d29fe5eb6d40d9c6f9b3f1b5ff1a6b1 does not exist."}`}
 
 	// send a payload manually, which has an invalid signature
-	// this should trigger a specific error, like  "User or API Wallet 0xXXXX does not exist"
+	// the exchange answers with a specific error, like  "User or API Wallet 0xXXXX does not exist"
 	// this error is in the response field, but it's a string
 	// this tests the unmarshalPlaceOrderResponse method, which should set ResponseErr accordingly
 	payload := ExchangeRequest{
@@ -407,17 +466,17 @@ func Test_Cancel_OuterError(t *testing.T) {
 				},
 			},
 		},
-		Nonce: GetNonce(),
+		Nonce: 1700000000000,
 		Signature: RsvSignature{
 			R: "0xa9a7cf2b26c9fa22b8e943f8bec93dd091b10c9d2f32e9bd98b70edaec9b908e",
 			S: "0x1e2ba41a0a32e1ac23a9e63ede05afd06994f1e269b381d9edfb13c9dd4485ee",
 			V: 27,
 		},
 	}
-	response := baseClient.Post(ctx, "/exchange", payload)
+	response, err := mock.Post(ctx, "/exchange", payload)
+	require.NoError(t, err)
 
-	m, _ := json.Marshal(response)
-	placeOrderResponse, err := unmarshalCancelOrderResponse(m)
+	placeOrderResponse, err := unmarshalCancelOrderResponse(response)
 	require.NoError(t, err)
 	require.Nil(t, placeOrderResponse.Response)
 	require.Contains(t, *placeOrderResponse.ResponseErr, "L1 error: User or API Wallet")
@@ -442,4 +501,1118 @@ func TestSizeAndPriceToWire(t *testing.T) {
 	// for MEW
 	require.Equal(t, "2840522", SizeToWire(2840522, 0))
 	require.Equal(t, "2840522", SizeToWire(2840522.1, 0))
+
+	// perps allow at most 6 - szDecimals decimals, spot 8 - szDecimals
+	require.Equal(t, "12.34", PriceToWire(12.345, 4))
+	require.Equal(t, "12.345", SpotPriceToWire(12.345, 4))
+	require.Equal(t, "0.0012", PriceToWire(0.0012345, 2))
+	require.Equal(t, "0.001234", SpotPriceToWire(0.0012345, 2))
+
+	// prices below 1 are also limited to 5 significant figures and MAX_DECIMALS - szDecimals decimals
+	require.Equal(t, "0.012345", SpotPriceToWire(0.012345678, 0))
+	require.Equal(t, "0.0012345", SpotPriceToWire(0.00123456789, 0))
+	require.Equal(t, "0.123", SpotPriceToWire(0.123456, 5))
+	require.Equal(t, "0.12345", PriceToWire(0.123456, 0))
+	require.Equal(t, "0.12", PriceToWire(0.123456, 4))
+	require.Equal(t, "0.000012", PriceToWire(0.0000123456, 0))
+
+	// integer prices are valid with any number of digits
+	require.Equal(t, "105000", PriceToWire(105000, 5))
+	require.Equal(t, "105000", PriceToWire(105000.7, 5))
+	require.Equal(t, "12345", PriceToWire(12345.6, 5))
+
+	_, err := OrderRequestToWire(OrderRequest{Coin: "BTC", LimitPx: math.NaN()}, map[string]AssetInfo{"BTC": {SzDecimals: 5}})
+	require.Error(t, err)
+}
+
+func TestSpotMeta(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockApi{responses: []string{
+		`{"universe":[{"name":"BTC","szDecimals":5},{"name":"ETH","szDecimals":4}]}`,
+		`{"tokens":[{"name":"USDC","szDecimals":8,"weiDecimals":8,"index":0},
+			{"name":"PURR","szDecimals":0,"weiDecimals":5,"index":1},
+			{"name":"HYPE","szDecimals":2,"weiDecimals":8,"index":150}],
+		"universe":[{"name":"PURR/USDC","tokens":[1,0],"index":0,"isCanonical":true},
+			{"name":"@107","tokens":[150,0],"index":107,"isCanonical":false}]}`,
+	}}
+	var api API = mock
+
+	meta, err := BuildMetaMap(ctx, NewInfoApi(&api))
+	require.NoError(t, err)
+	require.Equal(t, AssetInfo{Name: "ETH", SzDecimals: 4, AssetId: 1}, meta["ETH"])
+	require.Equal(t, AssetInfo{Name: "PURR/USDC", SzDecimals: 0, AssetId: 10000, IsSpot: true}, meta["PURR/USDC"])
+	require.Equal(t, AssetInfo{Name: "@107", SzDecimals: 2, AssetId: 10107, IsSpot: true}, meta["@107"])
+	require.Equal(t, meta["@107"], meta["HYPE/USDC"])
+
+	wire, err := OrderRequestToWire(OrderRequest{
+		Coin:      "HYPE/USDC",
+		IsBuy:     true,
+		Sz:        1.234,
+		LimitPx:   0.0012345,
+		OrderType: OrderType{Limit: &LimitOrderType{Tif: "Gtc"}},
+	}, meta)
+	require.NoError(t, err)
+	require.Equal(t, 10107, wire.Asset)
+	require.Equal(t, "0.001234", wire.LimitPx)
+	require.Equal(t, "1.23", wire.SizePx)
+
+	_, err = OrderRequestToWire(OrderRequest{Coin: "UNKNOWN/USDC"}, meta)
+	var unknownErr *UnknownAssetError
+	require.ErrorAs(t, err, &unknownErr)
+}
+
+// mockApi records the posted payloads and answers with the queued responses, then with response
+type mockApi struct {
+	payloads  []any
+	responses []string
+	response  string
+}
+
+func (m *mockApi) Post(ctx context.Context, path string, payload any) (json.RawMessage, error) {
+	m.payloads = append(m.payloads, payload)
+	if len(m.responses) > 0 {
+		res := m.responses[0]
+		m.responses = m.responses[1:]
+		return json.RawMessage(res), nil
+	}
+	return json.RawMessage(m.response), nil
+}
+
+func (m *mockApi) IsMainnet() bool {
+	return false
+}
+
+func newMockExchange(response string) (*ExchangeImpl, *mockApi) {
+	mock := &mockApi{response: response}
+	var api API = mock
+	return &ExchangeImpl{
+		infoApi:    NewInfoApi(&api),
+		cli:        &api,
+		meta:       map[string]AssetInfo{"ETH": {SzDecimals: 4, AssetId: 1}},
+		keyManager: &keyManager,
+		logger:     logger,
+		nonces:     NewMemoryNonceSource(),
+	}, mock
+}
+
+func TestVaultAddress(t *testing.T) {
+	ctx := context.Background()
+	vault := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"default"}}`)
+
+	_, err := exchange.UpdateLeverage(ctx, UpdateLeverageRequest{
+		Address:  Address,
+		Coin:     "ETH",
+		IsCross:  true,
+		Leverage: 3,
+	}, WithVaultAddress(vault))
+	require.NoError(t, err)
+
+	payload := mock.payloads[0].(ExchangeRequest)
+	require.Equal(t, vault, *payload.VaultAddress)
+
+	v, r, s, err := exchange.SignL1Action(ctx, Address, payload.Action, vault, payload.Nonce, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, ToTypedSig(r, s, v), payload.Signature)
+
+	v, r, s, err = exchange.SignL1Action(ctx, Address, payload.Action, "", payload.Nonce, nil, false)
+	require.NoError(t, err)
+	require.NotEqual(t, ToTypedSig(r, s, v), payload.Signature)
+}
+
+func TestL2Book(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockApi{response: `{"coin":"ETH","time":1700000000000,"levels":[
+		[{"px":"99","sz":"1","n":1},{"px":"98","sz":"2","n":3}],
+		[{"px":"101","sz":"1","n":2},{"px":"102","sz":"2","n":1},{"px":"104","sz":"5","n":4}]]}`}
+	var api API = mock
+
+	nSigFigs := 5
+	book, err := NewInfoApi(&api).GetL2Book(ctx, "ETH", &nSigFigs, nil)
+	require.NoError(t, err)
+	require.Equal(t, GetL2BookRequest{Typez: "l2Book", Coin: "ETH", NSigFigs: &nSigFigs}, mock.payloads[0])
+
+	bid, ok := book.BestBid()
+	require.True(t, ok)
+	require.Equal(t, "99", bid.Px)
+	ask, ok := book.BestAsk()
+	require.True(t, ok)
+	require.Equal(t, "101", ask.Px)
+
+	spread, err := book.Spread()
+	require.NoError(t, err)
+	require.Equal(t, 2.0, spread)
+
+	depth, err := book.DepthAtPrice(true, 102)
+	require.NoError(t, err)
+	require.Equal(t, 3.0, depth)
+	depth, err = book.DepthAtPrice(false, 98)
+	require.NoError(t, err)
+	require.Equal(t, 3.0, depth)
+
+	vwap, err := book.VWAP(true, 2)
+	require.NoError(t, err)
+	require.Equal(t, 101.5, vwap)
+
+	slippage, err := book.SlippageToFill(true, 4)
+	require.NoError(t, err)
+	require.InDelta(t, 0.04, slippage, 1e-9)
+
+	_, err = book.VWAP(false, 4)
+	require.ErrorIs(t, err, ErrInsufficientLiquidity)
+	_, err = book.VWAP(true, 0)
+	require.ErrorIs(t, err, ErrInvalidSize)
+	_, err = book.SlippageToFill(false, -1)
+	require.ErrorIs(t, err, ErrInvalidSize)
+	_, err = L2Book{}.Mid()
+	require.ErrorIs(t, err, ErrEmptyBook)
+}
+
+func TestCandlesPagination(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockApi{responses: []string{
+		`[{"t":0,"T":59999,"s":"ETH","i":"1m","o":"1","c":"2","h":"3","l":"0.5","v":"10","n":4},
+		  {"t":60000,"T":119999,"s":"ETH","i":"1m","o":"2","c":"3","h":"3","l":"2","v":"5","n":2}]`,
+		`[{"t":120000,"T":179999,"s":"ETH","i":"1m","o":"3","c":"3","h":"3","l":"3","v":"0","n":0}]`,
+	}}
+	var api API = mock
+
+	candles, err := NewInfoApi(&api).GetCandles(ctx, "ETH", "1m", 0, 179999)
+	require.NoError(t, err)
+	require.Len(t, candles, 3)
+	require.Equal(t, int64(120000), candles[2].OpenTime)
+	require.Len(t, mock.payloads, 2)
+	require.Equal(t, int64(120000), mock.payloads[1].(GetCandlesRequest).Req.StartTime)
+}
+
+func TestFundingHistoryPagination(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockApi{
+		responses: []string{
+			`[{"coin":"ETH","fundingRate":"0.0000125","premium":"0.0001","time":3600000},
+			  {"coin":"ETH","fundingRate":"0.0000130","premium":"0.0002","time":7200000}]`,
+		},
+		response: `[]`,
+	}
+	var api API = mock
+
+	rates, err := NewInfoApi(&api).GetFundingHistory(ctx, "ETH", 0, 36000000)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	require.Equal(t, "0.0000130", rates[1].FundingRate)
+	require.Len(t, mock.payloads, 2)
+	require.Equal(t, int64(7200001), mock.payloads[1].(GetFundingHistoryRequest).StartTime)
+}
+
+func TestMetaAndAssetCtxs(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockApi{response: `[
+		{"universe":[{"name":"BTC","szDecimals":5,"maxLeverage":50},{"name":"ETH","szDecimals":4,"maxLeverage":25,"onlyIsolated":true}]},
+		[{"funding":"0.0000125","openInterest":"100.5","prevDayPx":"60000.0","dayNtlVlm":"1000000.0","premium":"0.0003",
+		  "oraclePx":"61000.0","markPx":"61010.5","midPx":"61011.0","impactPxs":["61010.0","61012.0"],"dayBaseVlm":"16.4"},
+		 {"funding":"0.00001","openInterest":"2000.1","prevDayPx":"3000.0","dayNtlVlm":"500000.0","premium":null,
+		  "oraclePx":"3001.25","markPx":"3001.123456","midPx":null,"impactPxs":null,"dayBaseVlm":"166.6"}]]`}
+	var api API = mock
+	info := NewInfoApi(&api)
+
+	result, err := info.GetMetaAndAssetCtxs(ctx)
+	require.NoError(t, err)
+	require.Len(t, result.Universe, 2)
+	require.Len(t, result.Contexts, 2)
+
+	asset, assetCtx, ok := result.Asset("eth")
+	require.True(t, ok)
+	require.Equal(t, 25, asset.MaxLeverage)
+	require.True(t, asset.OnlyIsolated)
+	require.Nil(t, assetCtx.Premium)
+	require.Equal(t, "2000.1", assetCtx.OpenInterest)
+
+	markPx, err := info.GetMarkPx(ctx, "ETH")
+	require.NoError(t, err)
+	require.Equal(t, 3001.123456, markPx)
+	oraclePx, err := info.GetOraclePx(ctx, "BTC")
+	require.NoError(t, err)
+	require.Equal(t, 61000.0, oraclePx)
+
+	_, err = info.GetMarkPx(ctx, "DOGE")
+	var unknownErr *UnknownAssetError
+	require.ErrorAs(t, err, &unknownErr)
+}
+
+func TestSlippagePriceSource(t *testing.T) {
+	ctx := context.Background()
+	metaAndCtxs := `[{"universe":[{"name":"BTC","szDecimals":5,"maxLeverage":50},{"name":"ETH","szDecimals":4,"maxLeverage":25}]},
+		[{"oraclePx":"61000.0","markPx":"61010.5","midPx":"61011.0"},{"oraclePx":"3001.25","markPx":"3001.123456","midPx":"3000.0"}]]`
+	exchange, mock := newMockExchange("")
+
+	mock.responses = []string{`{"ETH":"3000.0"}`, metaAndCtxs, metaAndCtxs}
+	px, err := exchange.SlippagePrice(ctx, "ETH", true, 0.05, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3150.0, px)
+	px, err = exchange.SlippagePrice(ctx, "ETH", true, 0.05, nil, WithPriceSource(PriceSourceMark))
+	require.NoError(t, err)
+	require.Equal(t, 3151.2, px)
+	px, err = exchange.SlippagePrice(ctx, "ETH", false, 0.05, nil, WithPriceSource(PriceSourceOracle))
+	require.NoError(t, err)
+	require.Equal(t, 2851.2, px)
+
+	sz := 0.1
+	mock.responses = []string{metaAndCtxs, `{"status":"ok","response":{"type":"order","data":{"statuses":[{"resting":{"oid":1}}]}}}`}
+	_, err = exchange.MarketOpen(ctx, OpenRequest{Address: Address, Coin: "ETH", IsBuy: true, Sz: &sz}, WithPriceSource(PriceSourceOracle))
+	require.NoError(t, err)
+	order := mock.payloads[len(mock.payloads)-1].(ExchangeRequest).Action.(PlaceOrderAction)
+	require.Equal(t, "3151.3", order.Orders[0].LimitPx)
+
+	_, err = exchange.SlippagePrice(ctx, "ETH", true, 0.05, nil, WithPriceSource("last"))
+	require.Error(t, err)
+}
+
+func TestUserFillsIterator(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockApi{responses: []string{
+		`[{"coin":"ETH","tid":1,"time":100},{"coin":"ETH","tid":2,"time":200},{"coin":"ETH","tid":3,"time":200}]`,
+		`[{"coin":"ETH","tid":2,"time":200},{"coin":"ETH","tid":3,"time":200},{"coin":"BTC","tid":4,"time":300}]`,
+		`[{"coin":"BTC","tid":4,"time":300}]`,
+	}}
+	var api API = mock
+
+	it := NewUserFillsIterator(NewInfoApi(&api), Address, 0, 1000, false)
+	require.True(t, it.Next(ctx))
+	require.Len(t, it.Fills(), 3)
+
+	fills, err := it.All(ctx)
+	require.NoError(t, err)
+	require.Len(t, fills, 1)
+	require.Equal(t, int64(4), fills[0].Tid)
+
+	require.Len(t, mock.payloads, 3)
+	require.Equal(t, int64(200), mock.payloads[1].(GetUserFillsByTimeRequest).StartTime)
+	require.Equal(t, int64(300), mock.payloads[2].(GetUserFillsByTimeRequest).StartTime)
+	require.False(t, it.Next(ctx))
+}
+
+func TestHistoricalOrders(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockApi{response: `[{"order":{"coin":"ETH","side":"B","limitPx":"3000.0","sz":"0.0","oid":12,"timestamp":1700000000000,
+		"triggerCondition":"N/A","isTrigger":false,"triggerPx":"0.0","isPositionTpsl":false,"reduceOnly":false,
+		"orderType":"Limit","origSz":"1.5","tif":"Gtc","cloid":"0x00000000000000000000000000000001",
+		"children":[{"coin":"ETH","side":"A","limitPx":"3300.0","sz":"1.5","oid":13,"isTrigger":true,"triggerPx":"3300.0",
+			"triggerCondition":"Price above 3300","reduceOnly":true,"orderType":"Take Profit Market","children":[]}]},
+		"status":"filled","statusTimestamp":1700000001000}]`}
+	var api API = mock
+
+	orders, err := NewInfoApi(&api).GetHistoricalOrders(ctx, Address)
+	require.NoError(t, err)
+	require.Equal(t, GetInfoRequest{User: &[]string{Address}[0], Typez: "historicalOrders"}, mock.payloads[0])
+	require.Len(t, orders, 1)
+	require.Equal(t, "filled", orders[0].Status)
+	require.Equal(t, "1.5", orders[0].Order.OrigSz)
+	require.Equal(t, "Gtc", orders[0].Order.Tif)
+	require.Len(t, orders[0].Order.Children, 1)
+	require.True(t, orders[0].Order.Children[0].ReduceOnly)
+	require.Equal(t, "3300.0", orders[0].Order.Children[0].TriggerPx)
+}
+
+func TestBulkCancel(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange("")
+	exchange.meta["BTC"] = AssetInfo{SzDecimals: 5, AssetId: 0}
+	mock.responses = []string{
+		`{"status":"ok","response":{"type":"cancel","data":{"statuses":["success",{"error":"Order was never placed, already canceled, or filled."}]}}}`,
+		`{"status":"ok","response":{"type":"cancel","data":{"statuses":["success"]}}}`,
+	}
+
+	oid1, oid2, cloid := int64(11), int64(12), "0x00000000000000000000000000000001"
+	requests := []CancelRequest{
+		{Coin: "ETH", Oid: &oid1},
+		{Coin: "BTC", Cloid: &cloid},
+		{Coin: "BTC", Oid: &oid2},
+	}
+	results, err := exchange.BulkCancel(ctx, Address, requests)
+	require.NoError(t, err)
+	require.Len(t, mock.payloads, 2)
+	require.Equal(t, CancelOidOrderAction{Type: "cancel", Cancels: []CancelOidWire{{Asset: 1, Oid: 11}, {Asset: 0, Oid: 12}}},
+		mock.payloads[0].(ExchangeRequest).Action)
+	require.Equal(t, CancelCloidOrderAction{Type: "cancelByCloid", Cancels: []CancelCloidWire{{Asset: 0, Cloid: cloid}}},
+		mock.payloads[1].(ExchangeRequest).Action)
+
+	require.Len(t, results, 3)
+	require.Equal(t, requests[0], results[0].Request)
+	require.NoError(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+	var orderErr *OrderError
+	require.ErrorAs(t, results[2].Err, &orderErr)
+
+	_, err = exchange.BulkCancel(ctx, Address, []CancelRequest{{Coin: "ETH"}})
+	require.Error(t, err)
+}
+
+func TestCancelAll(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange("")
+	exchange.meta["BTC"] = AssetInfo{SzDecimals: 5, AssetId: 0}
+	mock.responses = []string{
+		`[{"coin":"ETH","oid":1},{"coin":"BTC","oid":2},{"coin":"ETH","oid":3}]`,
+		`{"status":"ok","response":{"type":"cancel","data":{"statuses":["success","success"]}}}`,
+	}
+
+	results, err := exchange.CancelAll(ctx, Address, []string{"ETH"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, int64(3), *results[1].Request.Oid)
+	require.Equal(t, CancelOidOrderAction{Type: "cancel", Cancels: []CancelOidWire{{Asset: 1, Oid: 1}, {Asset: 1, Oid: 3}}},
+		mock.payloads[1].(ExchangeRequest).Action)
+}
+
+func TestScheduleCancel(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"default"}}`)
+
+	at := time.UnixMilli(1700000000000)
+	_, err := exchange.ScheduleCancel(ctx, Address, &at)
+	require.NoError(t, err)
+	ms := int64(1700000000000)
+	require.Equal(t, ScheduleCancelAction{Type: "scheduleCancel", Time: &ms}, mock.payloads[0].(ExchangeRequest).Action)
+
+	_, err = exchange.ScheduleCancel(ctx, Address, nil)
+	require.NoError(t, err)
+	body, err := json.Marshal(mock.payloads[1].(ExchangeRequest).Action)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"scheduleCancel"}`, string(body))
+
+	// the exchange rejects cancels scheduled sooner, so every refresh would fail
+	sent := len(mock.payloads)
+	err = exchange.ScheduleCancelHeartbeat(ctx, Address, 4*time.Second, time.Second)
+	require.Error(t, err)
+	require.Len(t, mock.payloads, sent)
+
+	ctx, cancel := context.WithTimeout(ctx, 35*time.Millisecond)
+	defer cancel()
+	err = exchange.ScheduleCancelHeartbeat(ctx, Address, time.Minute, 10*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.GreaterOrEqual(t, len(mock.payloads), 4)
+	last := mock.payloads[len(mock.payloads)-1].(ExchangeRequest).Action.(ScheduleCancelAction)
+	require.Greater(t, *last.Time, time.Now().Add(50*time.Second).UnixMilli())
+}
+
+func TestTwap(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange("")
+	mock.responses = []string{
+		`{"status":"ok","response":{"type":"twapOrder","data":{"status":{"running":{"twapId":77738308}}}}}`,
+		`{"status":"ok","response":{"type":"twapCancel","data":{"status":"success"}}}`,
+		`{"status":"ok","response":{"type":"twapCancel","data":{"status":{"error":"TWAP was never placed, already canceled, or filled."}}}}`,
+		`[{"fill":{"coin":"ETH","px":"3000.1","sz":"0.25","tid":5},"twapId":77738308}]`,
+	}
+
+	response, err := exchange.TwapOrder(ctx, Address, "ETH", true, 1.23456, 30, false, false)
+	require.NoError(t, err)
+	twapId, ok := response.TwapId()
+	require.True(t, ok)
+	require.Equal(t, int64(77738308), twapId)
+	body, err := json.Marshal(mock.payloads[0].(ExchangeRequest).Action)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"twapOrder","twap":{"a":1,"b":true,"s":"1.2345","r":false,"m":30,"t":false}}`, string(body))
+
+	cancelled, err := exchange.TwapCancel(ctx, Address, "ETH", twapId)
+	require.NoError(t, err)
+	require.True(t, cancelled.IsCancelled())
+	require.Equal(t, TwapCancelAction{Type: "twapCancel", Asset: 1, TwapId: 77738308}, mock.payloads[1].(ExchangeRequest).Action)
+
+	cancelled, err = exchange.TwapCancel(ctx, Address, "ETH", twapId)
+	require.NoError(t, err)
+	require.False(t, cancelled.IsCancelled())
+	require.NotNil(t, cancelled.Response.Data.Status.Error)
+
+	fills, err := exchange.infoApi.GetTwapSliceFills(ctx, Address)
+	require.NoError(t, err)
+	require.Equal(t, int64(77738308), fills[0].TwapId)
+	require.Equal(t, "0.25", fills[0].Fill.Sz)
+}
+
+func TestIsolatedMargin(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"default"}}`)
+
+	_, err := exchange.UpdateIsolatedMargin(ctx, Address, "ETH", true, -12.5)
+	require.NoError(t, err)
+	require.Equal(t, UpdateIsolatedMarginAction{Type: "updateIsolatedMargin", Asset: 1, IsBuy: true, Ntli: -12500000},
+		mock.payloads[0].(ExchangeRequest).Action)
+
+	position := Position{
+		Coin:          "ETH",
+		Szi:           "-2.0",
+		EntryPx:       "3000",
+		LiquidationPx: "3500",
+		MaxLeverage:   25,
+		Leverage:      Leverage{Type: "isolated", Value: 10},
+	}
+	// short liquidation moving up needs margin: 100 * 2 * (1 + 1/50)
+	delta, err := MarginDeltaForLiquidationPx(position, 3600)
+	require.NoError(t, err)
+	require.InDelta(t, 204, delta, 1e-9)
+
+	mock.responses = []string{`{"assetPositions":[{"type":"oneWay","position":{"coin":"ETH","szi":"-2.0","liquidationPx":"3500",
+		"maxLeverage":25,"leverage":{"type":"isolated","value":10}}}]}`}
+	_, err = exchange.TargetLiquidationPx(ctx, Address, "ETH", 3450)
+	require.NoError(t, err)
+	require.Equal(t, UpdateIsolatedMarginAction{Type: "updateIsolatedMargin", Asset: 1, IsBuy: false, Ntli: -102000000},
+		mock.payloads[2].(ExchangeRequest).Action)
+
+	position.Leverage.Type = "cross"
+	_, err = MarginDeltaForLiquidationPx(position, 3600)
+	require.Error(t, err)
+}
+
+// recoverSigner returns the address which signed a user signed action with the given EIP-712 types
+func recoverSigner(t *testing.T, primaryType string, types []apitypes.Type, message apitypes.TypedDataMessage, sig RsvSignature) common.Address {
+	req := SigRequest{PrimaryType: primaryType, DType: types, DTypeMsg: message}
+	hash, _, err := apitypes.TypedDataAndHash(apitypes.TypedData{
+		Types:       GetContractTypes(req),
+		PrimaryType: primaryType,
+		Domain:      GetDomain(req),
+		Message:     message,
+	})
+	require.NoError(t, err)
+
+	raw := append(append(common.FromHex(sig.R), common.FromHex(sig.S)...), byte(sig.V-27))
+	pub, err := crypto.SigToPub(hash, raw)
+	require.NoError(t, err)
+	return crypto.PubkeyToAddress(*pub)
+}
+
+func TestTransfers(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"default"}}`)
+	destination := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+
+	response, err := exchange.UsdSend(ctx, UsdSendRequest{Address: Address, Destination: destination, Amount: 10.3})
+	require.NoError(t, err)
+	payload := mock.payloads[0].(ExchangeRequest)
+	require.Equal(t, payload.Nonce, response.Nonce)
+	action := payload.Action.(UsdSendAction)
+	require.Equal(t, UsdSendAction{Type: "usdSend", HLChain: "Testnet", SignatureChainId: "0x66eee",
+		Destination: destination, Amount: "10.3", Time: payload.Nonce}, action)
+	signer := recoverSigner(t, "HyperliquidTransaction:UsdSend", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "destination", Type: "string"},
+		{Name: "amount", Type: "string"},
+		{Name: "time", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain": "Testnet",
+		"destination":      destination,
+		"amount":           "10.3",
+		"time":             strconv.FormatInt(payload.Nonce, 10),
+	}, payload.Signature)
+	require.Equal(t, common.HexToAddress(Address), signer)
+
+	_, err = exchange.UsdClassTransfer(ctx, UsdClassTransferRequest{Address: Address, Amount: 5, ToPerp: true}, WithVaultAddress(destination))
+	require.NoError(t, err)
+	payload = mock.payloads[1].(ExchangeRequest)
+	require.Nil(t, payload.VaultAddress)
+	require.Equal(t, "5 subaccount:"+destination, payload.Action.(UsdClassTransferAction).Amount)
+	signer = recoverSigner(t, "HyperliquidTransaction:UsdClassTransfer", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "amount", Type: "string"},
+		{Name: "toPerp", Type: "bool"},
+		{Name: "nonce", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain": "Testnet",
+		"amount":           "5 subaccount:" + destination,
+		"toPerp":           true,
+		"nonce":            strconv.FormatInt(payload.Nonce, 10),
+	}, payload.Signature)
+	require.Equal(t, common.HexToAddress(Address), signer)
+
+	_, err = exchange.SubAccountTransfer(ctx, SubAccountTransferRequest{Address: Address, SubAccount: destination, IsDeposit: true, Amount: 1.5})
+	require.NoError(t, err)
+	require.Equal(t, SubAccountTransferAction{Type: "subAccountTransfer", SubAccountUser: destination, IsDeposit: true, Usd: 1500000},
+		mock.payloads[2].(ExchangeRequest).Action)
+
+	spotMeta := `{"tokens":[{"name":"PURR","szDecimals":0,"weiDecimals":5,"index":1,"tokenId":"0xc4bf3f870c0e9465323c0b6ed28096c2"}],"universe":[]}`
+	mock.responses = []string{spotMeta}
+	_, err = exchange.SpotSend(ctx, SpotSendRequest{Address: Address, Destination: destination, Token: "PURR:0xc4bf3f870c0e9465323c0b6ed28096c2", Amount: 2.123456})
+	require.NoError(t, err)
+	require.Equal(t, "2.12346", mock.payloads[4].(ExchangeRequest).Action.(SpotSendAction).Amount)
+
+	mock.responses = []string{spotMeta}
+	_, err = exchange.SpotSend(ctx, SpotSendRequest{Address: Address, Destination: destination, Token: "HFUN:0xbaf265ef389da684513d98d68edf4eae", Amount: 2})
+	var unknownErr *UnknownAssetError
+	require.ErrorAs(t, err, &unknownErr)
+
+	mock.responses = []string{spotMeta}
+	mock.response = `{"status":"err","response":"Insufficient balance for token transfer"}`
+	_, err = exchange.SpotSend(ctx, SpotSendRequest{Address: Address, Destination: destination, Token: "PURR:0xc4bf3f870c0e9465323c0b6ed28096c2", Amount: 2})
+	var exchangeErr *ExchangeError
+	require.ErrorAs(t, err, &exchangeErr)
+}
+
+func TestUserSignedActions(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange("")
+
+	action := WithdrawAction{Type: "withdraw3", HLChain: "Testnet", SignatureChainId: "0x66eee",
+		Destination: Address, Amount: "12.5", Time: 1700000000000}
+	v, r, s, err := exchange.SignUserSignedAction(ctx, Address, action)
+	require.NoError(t, err)
+	signer := recoverSigner(t, "HyperliquidTransaction:Withdraw", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "destination", Type: "string"},
+		{Name: "amount", Type: "string"},
+		{Name: "time", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain": "Testnet",
+		"destination":      Address,
+		"amount":           "12.5",
+		"time":             "1700000000000",
+	}, ToTypedSig(r, s, v))
+	require.Equal(t, common.HexToAddress(Address), signer)
+
+	_, _, _, err = exchange.SignUserSignedAction(ctx, Address, UpdateLeverageAction{Type: "updateLeverage"})
+	var signingErr *SigningError
+	require.ErrorAs(t, err, &signingErr)
+
+	// actions made for another network are rejected instead of signed
+	action.SignatureChainId = "0xa4b1"
+	_, _, _, err = exchange.SignUserSignedAction(ctx, Address, action)
+	require.ErrorAs(t, err, &signingErr)
+	action.SignatureChainId, action.HLChain = "0x66eee", "Mainnet"
+	_, _, _, err = exchange.SignUserSignedAction(ctx, Address, action)
+	require.ErrorAs(t, err, &signingErr)
+
+	// points requests claim the chain id of the network they are signed for
+	mock.response = `{}`
+	_, err = exchange.Points(ctx, Address)
+	require.NoError(t, err)
+	require.Equal(t, "0x66eee", mock.payloads[len(mock.payloads)-1].(PointsRequest).ChainId)
+
+	require.True(t, isUserSignedType("HyperliquidTransaction:UsdSend"))
+	require.False(t, isUserSignedType("Agent"))
+}
+
+type mapKeyManager map[string]*ecdsa.PrivateKey
+
+func (m mapKeyManager) GetKey(address string) *ecdsa.PrivateKey {
+	return m[strings.ToLower(address)]
+}
+
+func TestAgent(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"default"}}`)
+
+	approved, err := exchange.ApproveAgent(ctx, Address, "")
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(approved.AgentKey.PublicKey).Hex(), approved.AgentAddress)
+	payload := mock.payloads[0].(ExchangeRequest)
+	body, err := json.Marshal(payload.Action)
+	require.NoError(t, err)
+	require.NotContains(t, string(body), "agentName")
+	signer := recoverSigner(t, "HyperliquidTransaction:ApproveAgent", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "agentAddress", Type: "address"},
+		{Name: "agentName", Type: "string"},
+		{Name: "nonce", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain": "Testnet",
+		"agentAddress":     approved.AgentAddress,
+		"agentName":        "",
+		"nonce":            strconv.FormatInt(payload.Nonce, 10),
+	}, payload.Signature)
+	require.Equal(t, common.HexToAddress(Address), signer)
+
+	// the trading server only holds the agent key
+	var agentKeys KeyManager = mapKeyManager{strings.ToLower(approved.AgentAddress): approved.AgentKey}
+	exchange.keyManager = &agentKeys
+	WithAgent(Address, approved.AgentAddress)(exchange)
+
+	_, err = exchange.UpdateLeverage(ctx, UpdateLeverageRequest{Address: Address, Coin: "ETH", IsCross: true, Leverage: 2})
+	require.NoError(t, err)
+	payload = mock.payloads[1].(ExchangeRequest)
+	hash, err := exchange.buildActionHash(ctx, payload.Action, "", payload.Nonce, nil)
+	require.NoError(t, err)
+	signer = recoverSigner(t, "Agent", []apitypes.Type{
+		{Name: "source", Type: "string"},
+		{Name: "connectionId", Type: "bytes32"},
+	}, buildMessage(hash.Bytes(), false), payload.Signature)
+	require.Equal(t, common.HexToAddress(approved.AgentAddress), signer)
+}
+
+func TestBuilder(t *testing.T) {
+	ctx := context.Background()
+	builder := "0x1719884EB866cb12b2287399B15f7db5E7d775EA"
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"order","data":{"statuses":[{"resting":{"oid":1}}]}}}`)
+
+	order := OrderRequest{Coin: "ETH", IsBuy: true, Sz: 0.1, LimitPx: 3000, OrderType: OrderType{Limit: &LimitOrderType{Tif: "Gtc"}}}
+	_, err := exchange.Order(ctx, Address, order, GroupingNa, WithBuilder(builder, 10))
+	require.NoError(t, err)
+	action := mock.payloads[0].(ExchangeRequest).Action.(PlaceOrderAction)
+	require.Equal(t, &BuilderWire{Address: strings.ToLower(builder), Fee: 10}, action.Builder)
+
+	_, err = exchange.Order(ctx, Address, order, GroupingNa)
+	require.NoError(t, err)
+	body, err := json.Marshal(mock.payloads[1].(ExchangeRequest).Action)
+	require.NoError(t, err)
+	require.NotContains(t, string(body), "builder")
+
+	mock.response = `{"status":"ok","response":{"type":"default"}}`
+	_, err = exchange.ApproveBuilderFee(ctx, Address, builder, 10)
+	require.NoError(t, err)
+	payload := mock.payloads[2].(ExchangeRequest)
+	require.Equal(t, "0.01%", payload.Action.(ApproveBuilderFeeAction).MaxFeeRate)
+	signer := recoverSigner(t, "HyperliquidTransaction:ApproveBuilderFee", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "maxFeeRate", Type: "string"},
+		{Name: "builder", Type: "address"},
+		{Name: "nonce", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain": "Testnet",
+		"maxFeeRate":       "0.01%",
+		"builder":          builder,
+		"nonce":            strconv.FormatInt(payload.Nonce, 10),
+	}, payload.Signature)
+	require.Equal(t, common.HexToAddress(Address), signer)
+
+	mock.response = `10`
+	fee, err := exchange.infoApi.GetMaxBuilderFee(ctx, Address, builder)
+	require.NoError(t, err)
+	require.Equal(t, 10, fee)
+	require.Equal(t, GetMaxBuilderFeeRequest{Typez: "maxBuilderFee", User: Address, Builder: strings.ToLower(builder)}, mock.payloads[3])
+}
+
+func TestSubAccounts(t *testing.T) {
+	ctx := context.Background()
+	subAccount := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+	exchange, mock := newMockExchange("")
+	mock.responses = []string{
+		`{"status":"ok","response":{"type":"createSubAccount","data":"` + subAccount + `"}}`,
+		`{"status":"ok","response":{"type":"default"}}`,
+		`[{"name":"grid","subAccountUser":"` + subAccount + `","master":"` + strings.ToLower(Address) + `",
+			"clearinghouseState":{"withdrawable":"100.0","assetPositions":[{"type":"oneWay","position":{"coin":"ETH","szi":"0.5"}}]},
+			"spotState":{"balances":[{"coin":"USDC","token":0,"hold":"0.0","total":"25.5","entryNtl":"0.0"}]}}]`,
+		`null`,
+	}
+
+	created, err := exchange.CreateSubAccount(ctx, Address, "grid")
+	require.NoError(t, err)
+	require.Equal(t, subAccount, created)
+	require.Equal(t, CreateSubAccountAction{Type: "createSubAccount", Name: "grid"}, mock.payloads[0].(ExchangeRequest).Action)
+
+	_, err = exchange.SubAccountModify(ctx, Address, subAccount, "market-making")
+	require.NoError(t, err)
+	require.Equal(t, SubAccountModifyAction{Type: "subAccountModify", SubAccountUser: subAccount, Name: "market-making"},
+		mock.payloads[1].(ExchangeRequest).Action)
+
+	subAccounts, err := exchange.infoApi.GetSubAccounts(ctx, Address)
+	require.NoError(t, err)
+	require.Len(t, subAccounts, 1)
+	require.Equal(t, "0.5", subAccounts[0].ClearinghouseState.AssetPositions[0].Position.Szi)
+	require.Equal(t, "25.5", subAccounts[0].SpotState.Balances[0].Total)
+
+	subAccounts, err = exchange.infoApi.GetSubAccounts(ctx, subAccount)
+	require.NoError(t, err)
+	require.Empty(t, subAccounts)
+}
+
+func TestVaults(t *testing.T) {
+	ctx := context.Background()
+	vault := "0xdfc24b077bc1425ad1dea75bcb6f8158e10df303"
+	exchange, mock := newMockExchange("")
+	mock.responses = []string{
+		`{"status":"ok","response":{"type":"default"}}`,
+		`{"name":"HLP","vaultAddress":"` + vault + `","leader":"0x677d831aef5328190852e24f13c46cac05f984e7","description":"",
+			"portfolio":[["day",{"accountValueHistory":[[1700000000000,"100.5"],[1700000300000,"101.0"]],"pnlHistory":[[1700000000000,"0.0"]],"vlm":"0.0"}],
+				["allTime",{"accountValueHistory":[],"pnlHistory":[],"vlm":"1000.0"}]],
+			"apr":0.36,"followerState":{"user":"` + strings.ToLower(Address) + `","vaultEquity":"250.0","lockupUntil":1700086400000},
+			"leaderFraction":0.01,"leaderCommission":0,"followers":[{"user":"Leader","vaultEquity":"1000.0","daysFollowing":10}],
+			"maxDistributable":50.5,"maxWithdrawable":20.0,"isClosed":false,
+			"relationship":{"type":"parent","data":{"childAddresses":["0x010461c14e146ac35fe42271bdc1134ee31c703a"]}},
+			"allowDeposits":true,"alwaysCloseOnWithdraw":false}`,
+		`[{"vaultAddress":"` + vault + `","equity":"250.0","lockedUntilTimestamp":1700086400000}]`,
+	}
+
+	_, err := exchange.VaultTransfer(ctx, Address, vault, true, 250)
+	require.NoError(t, err)
+	require.Equal(t, VaultTransferAction{Type: "vaultTransfer", VaultAddress: vault, IsDeposit: true, Usd: 250000000},
+		mock.payloads[0].(ExchangeRequest).Action)
+
+	user := Address
+	details, err := exchange.infoApi.GetVaultDetails(ctx, vault, &user)
+	require.NoError(t, err)
+	require.Equal(t, GetVaultDetailsRequest{Typez: "vaultDetails", VaultAddress: vault, User: &user}, mock.payloads[1])
+	require.Equal(t, HistoryPoint{Time: 1700000300000, Value: "101.0"}, details.Portfolio["day"].AccountValueHistory[1])
+	require.Equal(t, "1000.0", details.Portfolio["allTime"].Vlm)
+	require.Equal(t, int64(1700086400000), details.FollowerState.LockupUntil)
+	require.Equal(t, 10, details.Followers[0].DaysFollowing)
+	require.Len(t, details.Relationship.Data.ChildAddresses, 1)
+
+	equities, err := exchange.infoApi.GetUserVaultEquities(ctx, Address)
+	require.NoError(t, err)
+	require.Equal(t, "250.0", equities[0].Equity)
+}
+
+func TestStaking(t *testing.T) {
+	ctx := context.Background()
+	validator := "0x5ac99df645f3414876c816caa18b2d234024b487"
+	exchange, mock := newMockExchange("")
+	mock.responses = []string{
+		`{"status":"ok","response":{"type":"default"}}`,
+		`{"status":"ok","response":{"type":"default"}}`,
+		`[{"validator":"` + validator + `","amount":"12060.16529862","lockedUntilTimestamp":1735466781353}]`,
+		`{"delegated":"12060.16529862","undelegated":"0.0","totalPendingWithdrawal":"0.0","nPendingWithdrawals":0}`,
+		`[{"time":1736726400073,"source":"delegation","totalAmount":"0.73117184"}]`,
+		`[{"validator":"` + validator + `","signer":"0x6277f45f4b3d3d6b3f3b2d3b1b7a0e7c8c2f0b4a","name":"Hypurr","description":"",
+			"nRecentBlocks":3000,"stake":1207390000000000,"isJailed":false,"unjailableAfter":null,"isActive":true,"commission":"0.04",
+			"stats":[["day",{"uptimeFraction":"1.0","predictedApr":"0.0256","nSamples":1440}],["week",{"uptimeFraction":"0.99","predictedApr":"0.025","nSamples":10080}]]}]`,
+	}
+
+	_, err := exchange.TokenDelegate(ctx, Address, validator, 100000000, false)
+	require.NoError(t, err)
+	payload := mock.payloads[0].(ExchangeRequest)
+	signer := recoverSigner(t, "HyperliquidTransaction:TokenDelegate", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "validator", Type: "address"},
+		{Name: "wei", Type: "uint64"},
+		{Name: "isUndelegate", Type: "bool"},
+		{Name: "nonce", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain": "Testnet",
+		"validator":        validator,
+		"wei":              "100000000",
+		"isUndelegate":     false,
+		"nonce":            strconv.FormatInt(payload.Nonce, 10),
+	}, payload.Signature)
+	require.Equal(t, common.HexToAddress(Address), signer)
+
+	_, err = exchange.CWithdraw(ctx, Address, 5)
+	require.NoError(t, err)
+	payload = mock.payloads[1].(ExchangeRequest)
+	require.Equal(t, "cWithdraw", payload.Action.(StakingTransferAction).Type)
+	signer = recoverSigner(t, "HyperliquidTransaction:CWithdraw", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "wei", Type: "uint64"},
+		{Name: "nonce", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain": "Testnet",
+		"wei":              "5",
+		"nonce":            strconv.FormatInt(payload.Nonce, 10),
+	}, payload.Signature)
+	require.Equal(t, common.HexToAddress(Address), signer)
+
+	delegations, err := exchange.infoApi.GetDelegations(ctx, Address)
+	require.NoError(t, err)
+	require.Equal(t, "12060.16529862", delegations[0].Amount)
+
+	summary, err := exchange.infoApi.GetDelegatorSummary(ctx, Address)
+	require.NoError(t, err)
+	require.Equal(t, "12060.16529862", summary.Delegated)
+
+	rewards, err := exchange.infoApi.GetDelegatorRewards(ctx, Address)
+	require.NoError(t, err)
+	require.Equal(t, "delegation", rewards[0].Source)
+
+	validators, err := exchange.infoApi.GetValidatorSummaries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1207390000000000), validators[0].Stake)
+	require.Equal(t, "0.025", validators[0].Stats["week"].PredictedApr)
+	require.Nil(t, validators[0].UnjailableAfter)
+}
+
+func TestMultiSig(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"order","data":{"statuses":[{"resting":{"oid":77}}]}}}`)
+	multiSigUser := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+	outerSigner := strings.ToLower(Address)
+	key, err := crypto.HexToECDSA(PrivateKey)
+	require.NoError(t, err)
+	cosignerKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	cosigner := crypto.PubkeyToAddress(cosignerKey.PublicKey)
+	var manager KeyManager = mapKeyManager{outerSigner: key, strings.ToLower(cosigner.Hex()): cosignerKey}
+
+	order := PlaceOrderAction{Type: "order", Grouping: GroupingNa, Orders: []OrderWire{{
+		Asset: 1, IsBuy: true, LimitPx: "1800.5", SizePx: "0.1", OrderType: OrderTypeWire{Limit: &LimitOrderType{Tif: "Gtc"}},
+	}}}
+	send := UsdSendAction{Type: "usdSend", SignatureChainId: "0x66eee", HLChain: "Testnet", Destination: Address, Amount: "1", Time: 1700000000000}
+	for _, action := range []any{order, send} {
+		data, err := json.Marshal(action)
+		require.NoError(t, err)
+		packed, err := packJSON(data)
+		require.NoError(t, err)
+		var expected bytes.Buffer
+		enc := msgpack.NewEncoder(&expected)
+		enc.UseCompactInts(true)
+		require.NoError(t, enc.Encode(action))
+		require.Equal(t, expected.Bytes(), packed)
+	}
+
+	tx, err := exchange.NewMultiSigTransaction(multiSigUser, Address, order, 1700000000000)
+	require.NoError(t, err)
+	require.NoError(t, tx.Sign(Address, manager))
+
+	// signatures are collected by sending the transaction around
+	data, err := json.Marshal(tx)
+	require.NoError(t, err)
+	var received MultiSigTransaction
+	require.NoError(t, json.Unmarshal(data, &received))
+	require.NoError(t, received.Sign(cosigner.Hex(), manager))
+	require.Len(t, received.Signatures, 2)
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.UseCompactInts(true)
+	require.NoError(t, enc.Encode([]any{multiSigUser, outerSigner, order}))
+	hash := hashPackedAction(buf.Bytes(), "", received.Nonce, nil)
+	agentTypes := []apitypes.Type{{Name: "source", Type: "string"}, {Name: "connectionId", Type: "bytes32"}}
+	require.Equal(t, common.HexToAddress(Address), recoverSigner(t, "Agent", agentTypes, buildMessage(hash.Bytes(), false), received.Signatures[0]))
+	require.Equal(t, cosigner, recoverSigner(t, "Agent", agentTypes, buildMessage(hash.Bytes(), false), received.Signatures[1]))
+
+	res, err := exchange.SendMultiSig(ctx, &received)
+	require.NoError(t, err)
+	require.Contains(t, string(res), `"oid":77`)
+	payload := mock.payloads[0].(ExchangeRequest)
+	action := payload.Action.(MultiSigAction)
+	require.Equal(t, "multiSig", action.Type)
+	require.Equal(t, received.Signatures, action.Signatures)
+
+	buf.Reset()
+	require.NoError(t, enc.Encode(struct {
+		SignatureChainId string         `msgpack:"signatureChainId"`
+		Signatures       []RsvSignature `msgpack:"signatures"`
+		Payload          struct {
+			MultiSigUser string           `msgpack:"multiSigUser"`
+			OuterSigner  string           `msgpack:"outerSigner"`
+			Action       PlaceOrderAction `msgpack:"action"`
+		} `msgpack:"payload"`
+	}{"0x66eee", received.Signatures, struct {
+		MultiSigUser string           `msgpack:"multiSigUser"`
+		OuterSigner  string           `msgpack:"outerSigner"`
+		Action       PlaceOrderAction `msgpack:"action"`
+	}{multiSigUser, outerSigner, order}}))
+	hash = hashPackedAction(buf.Bytes(), "", payload.Nonce, nil)
+	signer := recoverSigner(t, "HyperliquidTransaction:SendMultiSig", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "multiSigActionHash", Type: "bytes32"},
+		{Name: "nonce", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain":   "Testnet",
+		"multiSigActionHash": hash.Bytes(),
+		"nonce":              strconv.FormatInt(payload.Nonce, 10),
+	}, payload.Signature)
+	require.Equal(t, common.HexToAddress(Address), signer)
+
+	tx, err = exchange.NewMultiSigTransaction(multiSigUser, Address, send, send.Time)
+	require.NoError(t, err)
+	require.NoError(t, tx.Sign(cosigner.Hex(), manager))
+	signer = recoverSigner(t, "HyperliquidTransaction:UsdSend", []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "payloadMultiSigUser", Type: "address"},
+		{Name: "outerSigner", Type: "address"},
+		{Name: "destination", Type: "string"},
+		{Name: "amount", Type: "string"},
+		{Name: "time", Type: "uint64"},
+	}, apitypes.TypedDataMessage{
+		"hyperliquidChain":    "Testnet",
+		"payloadMultiSigUser": multiSigUser,
+		"outerSigner":         outerSigner,
+		"destination":         Address,
+		"amount":              "1",
+		"time":                "1700000000000",
+	}, tx.Signatures[0])
+	require.Equal(t, cosigner, signer)
+
+	mock.response = `{"status":"ok","response":{"type":"default"}}`
+	_, err = exchange.ConvertToMultiSigUser(ctx, Address, []string{cosigner.Hex(), Address}, 2)
+	require.NoError(t, err)
+	convert := mock.payloads[1].(ExchangeRequest).Action.(ConvertToMultiSigUserAction)
+	users := []string{strings.ToLower(cosigner.Hex()), outerSigner}
+	if users[0] > users[1] {
+		users[0], users[1] = users[1], users[0]
+	}
+	require.Equal(t, `{"authorizedUsers":["`+users[0]+`","`+users[1]+`"],"threshold":2}`, convert.Signers)
+
+	_, err = exchange.SendMultiSig(ctx, &MultiSigTransaction{MultiSigUser: multiSigUser, OuterSigner: Address, Action: data})
+	require.Error(t, err)
+}
+
+func TestNoop(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"default"}}`)
+
+	_, err := exchange.Noop(ctx, Address, 1700000000000)
+	require.NoError(t, err)
+	payload := mock.payloads[0].(ExchangeRequest)
+	require.Equal(t, int64(1700000000000), payload.Nonce)
+	require.Equal(t, NoopAction{Type: "noop"}, payload.Action)
+
+	cloid := "0x1234567890abcdef1234567890abcdef"
+	request := ExchangeRequest{Nonce: 1700000000001, Action: PlaceOrderAction{Type: "order", Grouping: GroupingNa, Orders: []OrderWire{{Cloid: &cloid}}}}
+	mock.responses = []string{
+		`{"status":"order","order":{"order":{"coin":"ETH","cloid":"` + cloid + `"},"status":"filled","statusTimestamp":1700000000002}}`,
+	}
+	resolution, err := exchange.ResolveRequest(ctx, Address, request)
+	require.NoError(t, err)
+	require.Equal(t, RequestExecuted, resolution.Outcome)
+	require.Equal(t, "filled", resolution.Orders[0].Order.Status)
+	require.Len(t, mock.payloads, 2)
+
+	mock.responses = []string{`{"status":"unknownOid"}`, `{"status":"ok","response":{"type":"default"}}`}
+	resolution, err = exchange.ResolveRequest(ctx, Address, request)
+	require.NoError(t, err)
+	require.Equal(t, RequestInvalidated, resolution.Outcome)
+	noop := mock.payloads[3].(ExchangeRequest)
+	require.Equal(t, request.Nonce, noop.Nonce)
+	require.Equal(t, NoopAction{Type: "noop"}, noop.Action)
+
+	// batch modifies are found by the cloids of their new orders
+	modify := ExchangeRequest{Nonce: 1700000000002, Action: &ModifyOrdersAction{Type: "batchModify",
+		Orders: []ModifyOrderWire{{OidOrCloid: int64(77738308), Order: OrderWire{Cloid: &cloid}}}}}
+	mock.responses = []string{
+		`{"status":"order","order":{"order":{"coin":"ETH","cloid":"` + cloid + `"},"status":"open","statusTimestamp":1700000000002}}`,
+	}
+	resolution, err = exchange.ResolveRequest(ctx, Address, modify)
+	require.NoError(t, err)
+	require.Equal(t, RequestExecuted, resolution.Outcome)
+	require.Equal(t, cloid, *mock.payloads[len(mock.payloads)-1].(GetInfoRequest).Oid)
+
+	mock.responses = []string{`{"status":"err","response":"Invalid nonce: duplicate nonce"}`}
+	resolution, err = exchange.ResolveRequest(ctx, Address, ExchangeRequest{Nonce: 1700000000003, Action: NoopAction{Type: "noop"}})
+	require.NoError(t, err)
+	require.Equal(t, RequestNonceUsed, resolution.Outcome)
+	require.Equal(t, "Invalid nonce: duplicate nonce", resolution.Message)
+}
+
+func TestNonceSource(t *testing.T) {
+	memory := NewMemoryNonceSource()
+	first, err := memory.Next(Address)
+	require.NoError(t, err)
+	second, err := memory.Next(strings.ToLower(Address))
+	require.NoError(t, err)
+	require.Greater(t, second, first)
+	other, err := memory.Next("0x1719884eb866cb12b2287399b15f7db5e7d775ea")
+	require.NoError(t, err)
+	require.LessOrEqual(t, other, second)
+
+	path := filepath.Join(t.TempDir(), "nonces.json")
+	source, err := NewFileNonceSource(path)
+	require.NoError(t, err)
+	future := time.Now().Add(time.Hour).UnixMilli()
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`{"%s":%d}`, strings.ToLower(Address), future)), 0o600))
+	nonce, err := source.Next(Address)
+	require.NoError(t, err)
+	require.Equal(t, future+1, nonce)
+
+	// a restarted process, or another one sharing the file, continues after the stored nonces
+	restarted, err := NewFileNonceSource(path)
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	nonces := make([]int64, 20)
+	errs := make([]error, len(nonces))
+	for i := range nonces {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				nonces[i], errs[i] = source.Next(Address)
+			} else {
+				nonces[i], errs[i] = restarted.Next(Address)
+			}
+		}(i)
+	}
+	wg.Wait()
+	seen := make(map[int64]bool)
+	for i, n := range nonces {
+		require.NoError(t, errs[i])
+		require.Greater(t, n, future+1)
+		require.False(t, seen[n])
+		seen[n] = true
+	}
+
+	// only the nonces and the lock file are left, temporary files are renamed or removed
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"0x`), 0o600))
+	_, err = NewFileNonceSource(path)
+	require.Error(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`{"%s":%d}`, strings.ToLower(Address), future+21)), 0o600))
+
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"default"}}`)
+	WithNonceSource(restarted)(exchange)
+	_, err = exchange.Noop(context.Background(), Address, 1)
+	require.NoError(t, err)
+	response, err := exchange.CDeposit(context.Background(), Address, 1)
+	require.NoError(t, err)
+	require.Equal(t, future+22, response.Nonce)
+	require.Equal(t, response.Nonce, mock.payloads[1].(ExchangeRequest).Nonce)
+}
+
+func TestExpiresAfter(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"default"}}`)
+
+	at := time.UnixMilli(1700000060000)
+	_, err := exchange.Noop(ctx, Address, 1700000000000, WithExpiresAfter(at))
+	require.NoError(t, err)
+	payload := mock.payloads[0].(ExchangeRequest)
+	require.Equal(t, int64(1700000060000), *payload.ExpiresAfter)
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `"expiresAfter":1700000060000`)
+
+	// msgpack of the action, nonce, no vault, then a zero byte and the expiry
+	data := append([]byte{0x81, 0xa4, 't', 'y', 'p', 'e', 0xa4, 'n', 'o', 'o', 'p'}, 0, 0, 0x01, 0x8b, 0xcf, 0xe5, 0x68, 0x00, 0x00)
+	data = append(data, 0x00, 0x00, 0x00, 0x01, 0x8b, 0xcf, 0xe6, 0x52, 0x60)
+	agentTypes := []apitypes.Type{{Name: "source", Type: "string"}, {Name: "connectionId", Type: "bytes32"}}
+	signer := recoverSigner(t, "Agent", agentTypes, buildMessage(crypto.Keccak256(data), false), payload.Signature)
+	require.Equal(t, common.HexToAddress(Address), signer)
+
+	WithExpiry(time.Minute)(exchange)
+	_, err = exchange.VaultTransfer(ctx, Address, "0xdfc24b077bc1425ad1dea75bcb6f8158e10df303", true, 10)
+	require.NoError(t, err)
+	expiresAfter := mock.payloads[1].(ExchangeRequest).ExpiresAfter
+	require.InDelta(t, time.Now().Add(time.Minute).UnixMilli(), *expiresAfter, 1000)
+
+	_, err = exchange.UsdSend(ctx, UsdSendRequest{Address: Address, Destination: Address, Amount: 1})
+	require.NoError(t, err)
+	require.Nil(t, mock.payloads[2].(ExchangeRequest).ExpiresAfter)
+
+	tx, err := exchange.NewMultiSigTransaction(Address, Address, NoopAction{Type: "noop"}, 1700000000000, WithExpiresAfter(at))
+	require.NoError(t, err)
+	require.Equal(t, int64(1700000060000), *tx.ExpiresAfter)
+
+	// the default expiry would start before the signatures are collected
+	tx, err = exchange.NewMultiSigTransaction(Address, Address, NoopAction{Type: "noop"}, 1700000000000)
+	require.NoError(t, err)
+	require.Nil(t, tx.ExpiresAfter)
+}
+
+func TestResolveRequestSigner(t *testing.T) {
+	ctx := context.Background()
+	exchange, mock := newMockExchange(`{"status":"ok","response":{"type":"default"}}`)
+	masterKey, err := crypto.HexToECDSA(PrivateKey)
+	require.NoError(t, err)
+	agentKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	agent := crypto.PubkeyToAddress(agentKey.PublicKey)
+	var manager KeyManager = mapKeyManager{strings.ToLower(Address): masterKey, strings.ToLower(agent.Hex()): agentKey}
+	exchange.keyManager = &manager
+	WithAgent(Address, agent.Hex())(exchange)
+	agentTypes := []apitypes.Type{{Name: "source", Type: "string"}, {Name: "connectionId", Type: "bytes32"}}
+
+	requests := map[common.Address]ExchangeRequest{
+		// user signed actions use the nonces of the master, even with an agent
+		common.HexToAddress(Address): {Nonce: 1700000000000, Action: UsdSendAction{Type: "usdSend", Time: 1700000000000}},
+		agent:                        {Nonce: 1700000000001, Action: ScheduleCancelAction{Type: "scheduleCancel"}},
+	}
+	for expected, request := range requests {
+		resolution, err := exchange.ResolveRequest(ctx, Address, request)
+		require.NoError(t, err)
+		require.Equal(t, RequestInvalidated, resolution.Outcome)
+
+		noop := mock.payloads[len(mock.payloads)-1].(ExchangeRequest)
+		hash, err := exchange.buildActionHash(ctx, NoopAction{Type: "noop"}, "", request.Nonce, nil)
+		require.NoError(t, err)
+		require.Equal(t, expected, recoverSigner(t, "Agent", agentTypes, buildMessage(hash.Bytes(), false), noop.Signature))
+	}
 }
@@ -0,0 +1,87 @@
+package hyperliquid
+
+import "context"
+
+// UserFillsIterator walks the fills of a user between two timestamps, one response window at a time.
+// Each window starts at the time of the last fill seen, so fills sharing that millisecond are not lost,
+// and fills already returned are skipped using their Tid.
+type UserFillsIterator struct {
+	info            InfoApi
+	address         string
+	start           int64
+	end             int64
+	aggregateByTime bool
+
+	seen  map[int64]struct{}
+	fills []OrderFill
+	err   error
+	done  bool
+}
+
+func NewUserFillsIterator(info InfoApi, address string, start int64, end int64, aggregateByTime bool) *UserFillsIterator {
+	return &UserFillsIterator{
+		info:            info,
+		address:         address,
+		start:           start,
+		end:             end,
+		aggregateByTime: aggregateByTime,
+		seen:            make(map[int64]struct{}),
+	}
+}
+
+// Next fetches the next window of fills, returning false when the range is exhausted or a request failed
+func (it *UserFillsIterator) Next(ctx context.Context) bool {
+	it.fills = nil
+	for !it.done && it.start <= it.end {
+		page, err := it.info.GetUserFillsByTime(ctx, it.address, it.start, it.end, it.aggregateByTime)
+		if err != nil {
+			it.err = err
+			it.done = true
+			return false
+		}
+
+		last := it.start
+		for _, fill := range page {
+			if fill.Time > last {
+				last = fill.Time
+			}
+			if _, ok := it.seen[fill.Tid]; ok {
+				continue
+			}
+			it.seen[fill.Tid] = struct{}{}
+			it.fills = append(it.fills, fill)
+		}
+
+		if len(it.fills) == 0 {
+			// either no fills are left, or the window only held fills already returned
+			if last == it.start {
+				it.done = true
+				return false
+			}
+		}
+		it.start = last
+		if len(it.fills) > 0 {
+			return true
+		}
+	}
+	it.done = true
+	return false
+}
+
+// Fills returns the new fills of the current window
+func (it *UserFillsIterator) Fills() []OrderFill {
+	return it.fills
+}
+
+func (it *UserFillsIterator) Err() error {
+	return it.err
+}
+
+// All walks the remaining windows and returns every fill
+func (it *UserFillsIterator) All(ctx context.Context) ([]OrderFill, error) {
+	var fills []OrderFill
+	for it.Next(ctx) {
+		fills = append(fills, it.Fills()...)
+	}
+	return fills, it.Err()
+}
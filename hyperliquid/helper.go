@@ -3,9 +3,12 @@ package hyperliquid
 import (
 	"context"
 	"crypto/rand"
+	"fmt"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"log"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -13,6 +16,9 @@ const MainnetUrl = "https://api.hyperliquid.xyz"
 const TestnetUrl = "https://api.hyperliquid-testnet.xyz"
 const DefaultSlippage = 0.05
 
+// SpotAssetOffset is added to the index of spot pairs to get their asset id
+const SpotAssetOffset = 10000
+
 func SigToVRS(sig []byte) (byte, [32]byte, [32]byte, error) {
 	var v byte
 	var r [32]byte
@@ -40,7 +46,7 @@ func GetContractTypes(req SigRequest) apitypes.Types {
 
 func GetDomain(req SigRequest) apitypes.TypedDataDomain {
 
-	if req.PrimaryType == "HyperliquidTransaction:Withdraw" || req.PrimaryType == "Hyperliquid:UserPoints" {
+	if isUserSignedType(req.PrimaryType) {
 		return apitypes.TypedDataDomain{
 			Name:              "HyperliquidSignTransaction",
 			Version:           "1",
@@ -68,15 +74,82 @@ func GetRandomCloid() string {
 	return hexutil.Encode(buf)
 }
 
-func BuildMetaMap(info InfoApi) map[string]AssetInfo {
+func BuildMetaMap(ctx context.Context, info InfoApi) (map[string]AssetInfo, error) {
+	meta, err := info.GetMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
 	metaMap := make(map[string]AssetInfo)
-	for index, asset := range info.GetMeta(context.Background()).Universe {
+	for index, asset := range meta.Universe {
 		i := AssetInfo{
+			Name:       asset.Name,
 			SzDecimals: asset.SzDecimals,
 			AssetId:    index,
 		}
 		metaMap[asset.Name] = i
 		metaMap[strings.ToUpper(asset.Name)] = i
 	}
-	return metaMap
+
+	spotMeta, err := info.GetSpotMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make(map[int]SpotToken)
+	for _, token := range spotMeta.Tokens {
+		tokens[token.Index] = token
+	}
+	for _, pair := range spotMeta.Universe {
+		base, okBase := tokens[pair.Tokens[0]]
+		quote, okQuote := tokens[pair.Tokens[1]]
+		if !okBase || !okQuote {
+			continue
+		}
+		i := AssetInfo{
+			Name:       pair.Name,
+			SzDecimals: base.SzDecimals,
+			AssetId:    SpotAssetOffset + pair.Index,
+			IsSpot:     true,
+		}
+		metaMap[pair.Name] = i
+		// non canonical pairs are named "@{index}", so they are also mapped by their tokens, e.g. "HYPE/USDC"
+		name := fmt.Sprintf("%s/%s", base.Name, quote.Name)
+		if _, ok := metaMap[name]; !ok {
+			metaMap[name] = i
+			metaMap[strings.ToUpper(name)] = i
+		}
+	}
+	return metaMap, nil
+}
+
+// MarginDeltaForLiquidationPx returns the margin to add to an isolated position, or to remove when negative,
+// to move its liquidation price to liquidationPx. Liquidation happens when the margin left is the maintenance
+// margin, which is half the initial margin at max leverage, so for side 1 on longs and -1 on shorts:
+//
+//	liquidationPx = px - side * availableMargin / |szi| / (1 - side / (2 * maxLeverage))
+func MarginDeltaForLiquidationPx(position Position, liquidationPx float64) (float64, error) {
+	if position.Leverage.Type != "isolated" {
+		return 0, fmt.Errorf("position in %s is not isolated", position.Coin)
+	}
+	if position.MaxLeverage <= 0 {
+		return 0, fmt.Errorf("position in %s has no max leverage", position.Coin)
+	}
+	szi, err := strconv.ParseFloat(position.Szi, 64)
+	if err != nil {
+		return 0, err
+	}
+	if szi == 0 {
+		return 0, fmt.Errorf("%w for asset %s", ErrNoPosition, position.Coin)
+	}
+	currentPx, err := strconv.ParseFloat(position.LiquidationPx, 64)
+	if err != nil {
+		return 0, fmt.Errorf("position in %s has no liquidation price: %w", position.Coin, err)
+	}
+
+	side := 1.0
+	if szi < 0 {
+		side = -1.0
+	}
+	l := 1 / (2 * float64(position.MaxLeverage))
+
+	return (currentPx - liquidationPx) * math.Abs(szi) * (1 - l*side) * side, nil
 }
@@ -3,21 +3,41 @@ package hyperliquid
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
 )
 
 type InfoApi interface {
-	GetUserState(ctx context.Context, address string) UserState
-	GetUserFills(ctx context.Context, address string) []OrderFill
-	GetNonFundingUpdates(ctx context.Context, address string) []NonFundingUpdate
-	GetFundingUpdates(ctx context.Context, address string) []FundingUpdate
-	GetWithdrawals(ctx context.Context, address string) []Withdrawal
-	FindOrder(ctx context.Context, address string, cloid string) OrderResponse
-	FindOpenOrders(ctx context.Context, address string) []OpenOrder
-	GetAllMids(ctx context.Context) map[string]string
-	GetMktPx(ctx context.Context, coin string) float64
-	GetMeta(ctx context.Context) Meta
+	GetUserState(ctx context.Context, address string) (UserState, error)
+	GetUserFills(ctx context.Context, address string) ([]OrderFill, error)
+	GetUserFillsByTime(ctx context.Context, address string, start int64, end int64, aggregateByTime bool) ([]OrderFill, error)
+	GetNonFundingUpdates(ctx context.Context, address string) ([]NonFundingUpdate, error)
+	GetFundingUpdates(ctx context.Context, address string) ([]FundingUpdate, error)
+	GetCandles(ctx context.Context, coin string, interval string, start int64, end int64) ([]Candle, error)
+	GetFundingHistory(ctx context.Context, coin string, start int64, end int64) ([]FundingRate, error)
+	GetWithdrawals(ctx context.Context, address string) ([]Withdrawal, error)
+	FindOrder(ctx context.Context, address string, cloid string) (OrderResponse, error)
+	FindOpenOrders(ctx context.Context, address string) ([]OpenOrder, error)
+	GetFrontendOpenOrders(ctx context.Context, address string) ([]FrontendOrder, error)
+	GetHistoricalOrders(ctx context.Context, address string) ([]OrderWithStatus, error)
+	GetTwapSliceFills(ctx context.Context, address string) ([]TwapSliceFill, error)
+	GetMaxBuilderFee(ctx context.Context, address string, builder string) (int, error)
+	GetSubAccounts(ctx context.Context, address string) ([]SubAccount, error)
+	GetDelegations(ctx context.Context, address string) ([]Delegation, error)
+	GetDelegatorSummary(ctx context.Context, address string) (DelegatorSummary, error)
+	GetDelegatorRewards(ctx context.Context, address string) ([]DelegatorReward, error)
+	GetValidatorSummaries(ctx context.Context) ([]ValidatorSummary, error)
+	GetVaultDetails(ctx context.Context, vault string, user *string) (VaultDetails, error)
+	GetUserVaultEquities(ctx context.Context, user string) ([]VaultEquity, error)
+	GetAllMids(ctx context.Context) (map[string]string, error)
+	GetMktPx(ctx context.Context, coin string) (float64, error)
+	GetMarkPx(ctx context.Context, coin string) (float64, error)
+	GetOraclePx(ctx context.Context, coin string) (float64, error)
+	GetMeta(ctx context.Context) (Meta, error)
+	GetMetaAndAssetCtxs(ctx context.Context) (MetaAndAssetCtxs, error)
+	GetSpotMeta(ctx context.Context) (SpotMeta, error)
+	GetL2Book(ctx context.Context, coin string, nSigFigs *int, mantissa *int) (L2Book, error)
 }
 
 type InfoApiDefault struct {
@@ -37,6 +57,27 @@ type UserState struct {
 	MarginSummary      MarginSummary   `json:"marginSummary"`
 }
 
+type SpotState struct {
+	Balances []SpotBalance `json:"balances"`
+}
+
+type SpotBalance struct {
+	Coin     string `json:"coin"`
+	Token    int    `json:"token"`
+	Hold     string `json:"hold"`
+	Total    string `json:"total"`
+	EntryNtl string `json:"entryNtl"`
+}
+
+// SubAccount is a sub-account of Master, with its perp and spot state
+type SubAccount struct {
+	Name               string    `json:"name"`
+	SubAccountUser     string    `json:"subAccountUser"`
+	Master             string    `json:"master"`
+	ClearinghouseState UserState `json:"clearinghouseState"`
+	SpotState          SpotState `json:"spotState"`
+}
+
 type AssetPosition struct {
 	Position Position `json:"position"`
 	Type     string   `json:"type"`
@@ -48,6 +89,7 @@ type Position struct {
 	Leverage       Leverage `json:"leverage"`
 	LiquidationPx  string   `json:"liquidationPx"`
 	MarginUsed     string   `json:"marginUsed"`
+	MaxLeverage    int      `json:"maxLeverage"`
 	PositionValue  string   `json:"positionValue"`
 	ReturnOnEquity string   `json:"returnOnEquity"`
 	Szi            string   `json:"szi"`
@@ -65,12 +107,58 @@ type GetUserStateRequest struct {
 	Typez string `json:"type"`
 }
 
+type GetVaultDetailsRequest struct {
+	Typez        string  `json:"type"`
+	VaultAddress string  `json:"vaultAddress"`
+	User         *string `json:"user,omitempty"`
+}
+
+type GetMaxBuilderFeeRequest struct {
+	Typez   string `json:"type"`
+	User    string `json:"user"`
+	Builder string `json:"builder"`
+}
+
 type GetInfoRequest struct {
 	User  *string `json:"user,omitempty"`
 	Typez string  `json:"type"`
 	Oid   *string `json:"oid,omitempty"`
 }
 
+type GetL2BookRequest struct {
+	Typez    string `json:"type"`
+	Coin     string `json:"coin"`
+	NSigFigs *int   `json:"nSigFigs,omitempty"`
+	Mantissa *int   `json:"mantissa,omitempty"`
+}
+
+type GetUserFillsByTimeRequest struct {
+	User            string `json:"user"`
+	Typez           string `json:"type"`
+	StartTime       int64  `json:"startTime"`
+	EndTime         int64  `json:"endTime"`
+	AggregateByTime bool   `json:"aggregateByTime"`
+}
+
+type GetCandlesRequest struct {
+	Typez string              `json:"type"`
+	Req   CandleSnapshotRange `json:"req"`
+}
+
+type CandleSnapshotRange struct {
+	Coin      string `json:"coin"`
+	Interval  string `json:"interval"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+}
+
+type GetFundingHistoryRequest struct {
+	Typez     string `json:"type"`
+	Coin      string `json:"coin"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+}
+
 type PointsRequest struct {
 	User      *string      `json:"user,omitempty"`
 	Typez     string       `json:"type"`
@@ -86,32 +174,28 @@ type MarginSummary struct {
 	TotalRawUsd     string `json:"totalRawUsd"`
 }
 
-func (api *InfoApiDefault) GetUserState(ctx context.Context, address string) UserState {
+func (api *InfoApiDefault) GetUserState(ctx context.Context, address string) (UserState, error) {
 	request := GetUserStateRequest{
 		User:  address,
 		Typez: "clearinghouseState",
 	}
-	anyResult := (*api.apiClient).Post(ctx, "/info", request)
-	parsed, _ := json.Marshal(anyResult)
-	var result UserState
-	_ = json.Unmarshal(parsed, &result)
-	return result
+	return postInfo[UserState](ctx, *api.apiClient, request)
 }
 
-func (api *InfoApiDefault) GetAllMids(ctx context.Context) map[string]string {
+func (api *InfoApiDefault) GetAllMids(ctx context.Context) (map[string]string, error) {
 	request := GetInfoRequest{
 		Typez: "allMids",
 	}
-	anyResult := (*api.apiClient).Post(ctx, "/info", request)
-	parsed, _ := json.Marshal(anyResult)
-	var result map[string]string
-	_ = json.Unmarshal(parsed, &result)
+	result, err := postInfo[map[string]string](ctx, *api.apiClient, request)
+	if err != nil {
+		return nil, err
+	}
 
 	for k, v := range result {
 		result[strings.ToUpper(k)] = v
 	}
 
-	return result
+	return result, nil
 }
 
 type Meta struct {
@@ -119,90 +203,411 @@ type Meta struct {
 }
 
 type Asset struct {
-	Name       string `json:"name"`
-	SzDecimals int    `json:"szDecimals"`
+	Name         string `json:"name"`
+	SzDecimals   int    `json:"szDecimals"`
+	MaxLeverage  int    `json:"maxLeverage"`
+	OnlyIsolated bool   `json:"onlyIsolated"`
+	IsDelisted   bool   `json:"isDelisted"`
+}
+
+type AssetCtx struct {
+	Funding      string   `json:"funding"`
+	OpenInterest string   `json:"openInterest"`
+	PrevDayPx    string   `json:"prevDayPx"`
+	DayNtlVlm    string   `json:"dayNtlVlm"`
+	DayBaseVlm   string   `json:"dayBaseVlm"`
+	Premium      *string  `json:"premium"`
+	OraclePx     string   `json:"oraclePx"`
+	MarkPx       string   `json:"markPx"`
+	MidPx        *string  `json:"midPx"`
+	ImpactPxs    []string `json:"impactPxs"`
+}
+
+func (c AssetCtx) MarkPrice() (float64, error) {
+	return strconv.ParseFloat(c.MarkPx, 64)
+}
+
+func (c AssetCtx) OraclePrice() (float64, error) {
+	return strconv.ParseFloat(c.OraclePx, 64)
+}
+
+// MetaAndAssetCtxs holds the perp universe and the context of each asset, Contexts[i] belongs to Universe[i]
+type MetaAndAssetCtxs struct {
+	Meta
+	Contexts []AssetCtx
+}
+
+// UnmarshalJSON reads the [meta, contexts] array returned by the API
+func (m *MetaAndAssetCtxs) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 2 {
+		return fmt.Errorf("expected meta and asset contexts, got %d elements", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &m.Meta); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[1], &m.Contexts)
+}
+
+// Asset returns the meta and context of a coin
+func (m MetaAndAssetCtxs) Asset(coin string) (Asset, AssetCtx, bool) {
+	for i, asset := range m.Universe {
+		if strings.EqualFold(asset.Name, coin) && i < len(m.Contexts) {
+			return asset, m.Contexts[i], true
+		}
+	}
+	return Asset{}, AssetCtx{}, false
 }
 
-func (api *InfoApiDefault) GetMeta(ctx context.Context) Meta {
+func (api *InfoApiDefault) GetMeta(ctx context.Context) (Meta, error) {
 	request := GetInfoRequest{
 		Typez: "meta",
 	}
-	anyResult := (*api.apiClient).Post(ctx, "/info", request)
-	parsed, _ := json.Marshal(anyResult)
-	var result Meta
-	_ = json.Unmarshal(parsed, &result)
-	return result
+	return postInfo[Meta](ctx, *api.apiClient, request)
+}
+
+func (api *InfoApiDefault) GetMetaAndAssetCtxs(ctx context.Context) (MetaAndAssetCtxs, error) {
+	request := GetInfoRequest{
+		Typez: "metaAndAssetCtxs",
+	}
+	return postInfo[MetaAndAssetCtxs](ctx, *api.apiClient, request)
+}
+
+type SpotMeta struct {
+	Tokens   []SpotToken `json:"tokens"`
+	Universe []SpotPair  `json:"universe"`
+}
+
+type SpotToken struct {
+	Name        string `json:"name"`
+	SzDecimals  int    `json:"szDecimals"`
+	WeiDecimals int    `json:"weiDecimals"`
+	Index       int    `json:"index"`
+	TokenId     string `json:"tokenId"`
+	IsCanonical bool   `json:"isCanonical"`
+}
+
+// SpotPair is a spot market. Name is "PURR/USDC" for canonical pairs and "@{index}" for the others.
+type SpotPair struct {
+	Name        string `json:"name"`
+	Tokens      [2]int `json:"tokens"`
+	Index       int    `json:"index"`
+	IsCanonical bool   `json:"isCanonical"`
+}
+
+func (api *InfoApiDefault) GetSpotMeta(ctx context.Context) (SpotMeta, error) {
+	request := GetInfoRequest{
+		Typez: "spotMeta",
+	}
+	return postInfo[SpotMeta](ctx, *api.apiClient, request)
+}
+
+// GetL2Book returns a book snapshot with up to 20 levels per side. nSigFigs (2 to 5) aggregates levels to
+// that many significant figures, and mantissa (1, 2 or 5) is only allowed when nSigFigs is 5.
+func (api *InfoApiDefault) GetL2Book(ctx context.Context, coin string, nSigFigs *int, mantissa *int) (L2Book, error) {
+	request := GetL2BookRequest{
+		Typez:    "l2Book",
+		Coin:     coin,
+		NSigFigs: nSigFigs,
+		Mantissa: mantissa,
+	}
+	return postInfo[L2Book](ctx, *api.apiClient, request)
 }
 
-func (api *InfoApiDefault) FindOrder(ctx context.Context, address string, cloid string) OrderResponse {
+func (api *InfoApiDefault) FindOrder(ctx context.Context, address string, cloid string) (OrderResponse, error) {
 	request := GetInfoRequest{
 		User:  &address,
 		Typez: "orderStatus",
 		Oid:   &cloid,
 	}
-	anyResult := (*api.apiClient).Post(ctx, "/info", request)
-	parsed, _ := json.Marshal(anyResult)
-	var result OrderResponse
-	_ = json.Unmarshal(parsed, &result)
-	return result
+	return postInfo[OrderResponse](ctx, *api.apiClient, request)
 }
 
-func (api *InfoApiDefault) FindOpenOrders(ctx context.Context, address string) []OpenOrder {
+func (api *InfoApiDefault) FindOpenOrders(ctx context.Context, address string) ([]OpenOrder, error) {
 	request := GetInfoRequest{
 		User:  &address,
 		Typez: "openOrders",
 	}
-	anyResult := (*api.apiClient).Post(ctx, "/info", request)
-	parsed, _ := json.Marshal(anyResult)
-	var result []OpenOrder
-	_ = json.Unmarshal(parsed, &result)
-	return result
+	return postInfo[[]OpenOrder](ctx, *api.apiClient, request)
+}
+
+// GetFrontendOpenOrders returns the open orders with their trigger, reduce only, tif and cloid information
+func (api *InfoApiDefault) GetFrontendOpenOrders(ctx context.Context, address string) ([]FrontendOrder, error) {
+	request := GetInfoRequest{
+		User:  &address,
+		Typez: "frontendOpenOrders",
+	}
+	return postInfo[[]FrontendOrder](ctx, *api.apiClient, request)
+}
+
+// GetHistoricalOrders returns at most the 2000 most recent orders with their final status
+func (api *InfoApiDefault) GetHistoricalOrders(ctx context.Context, address string) ([]OrderWithStatus, error) {
+	request := GetInfoRequest{
+		User:  &address,
+		Typez: "historicalOrders",
+	}
+	return postInfo[[]OrderWithStatus](ctx, *api.apiClient, request)
+}
+
+// GetTwapSliceFills returns at most the 2000 most recent fills of the orders TWAPs were split into
+func (api *InfoApiDefault) GetTwapSliceFills(ctx context.Context, address string) ([]TwapSliceFill, error) {
+	request := GetInfoRequest{
+		User:  &address,
+		Typez: "userTwapSliceFills",
+	}
+	return postInfo[[]TwapSliceFill](ctx, *api.apiClient, request)
+}
+
+// GetSubAccounts returns the sub-accounts of address, which is empty when it has none
+func (api *InfoApiDefault) GetSubAccounts(ctx context.Context, address string) ([]SubAccount, error) {
+	request := GetInfoRequest{
+		User:  &address,
+		Typez: "subAccounts",
+	}
+	return postInfo[[]SubAccount](ctx, *api.apiClient, request)
+}
+
+func (api *InfoApiDefault) GetDelegations(ctx context.Context, address string) ([]Delegation, error) {
+	request := GetInfoRequest{
+		User:  &address,
+		Typez: "delegations",
+	}
+	return postInfo[[]Delegation](ctx, *api.apiClient, request)
+}
+
+func (api *InfoApiDefault) GetDelegatorSummary(ctx context.Context, address string) (DelegatorSummary, error) {
+	request := GetInfoRequest{
+		User:  &address,
+		Typez: "delegatorSummary",
+	}
+	return postInfo[DelegatorSummary](ctx, *api.apiClient, request)
+}
+
+func (api *InfoApiDefault) GetDelegatorRewards(ctx context.Context, address string) ([]DelegatorReward, error) {
+	request := GetInfoRequest{
+		User:  &address,
+		Typez: "delegatorRewards",
+	}
+	return postInfo[[]DelegatorReward](ctx, *api.apiClient, request)
+}
+
+func (api *InfoApiDefault) GetValidatorSummaries(ctx context.Context) ([]ValidatorSummary, error) {
+	request := GetInfoRequest{
+		Typez: "validatorSummaries",
+	}
+	return postInfo[[]ValidatorSummary](ctx, *api.apiClient, request)
+}
+
+// GetVaultDetails returns a vault with its followers and performance, user adds the state of that follower
+func (api *InfoApiDefault) GetVaultDetails(ctx context.Context, vault string, user *string) (VaultDetails, error) {
+	request := GetVaultDetailsRequest{
+		Typez:        "vaultDetails",
+		VaultAddress: strings.ToLower(vault),
+		User:         user,
+	}
+	return postInfo[VaultDetails](ctx, *api.apiClient, request)
+}
+
+// GetUserVaultEquities returns the equity of user in each vault it deposited to
+func (api *InfoApiDefault) GetUserVaultEquities(ctx context.Context, user string) ([]VaultEquity, error) {
+	request := GetInfoRequest{
+		User:  &user,
+		Typez: "userVaultEquities",
+	}
+	return postInfo[[]VaultEquity](ctx, *api.apiClient, request)
+}
+
+// GetMaxBuilderFee returns the max fee, in tenths of a basis point, address approved for builder
+func (api *InfoApiDefault) GetMaxBuilderFee(ctx context.Context, address string, builder string) (int, error) {
+	request := GetMaxBuilderFeeRequest{
+		Typez:   "maxBuilderFee",
+		User:    address,
+		Builder: strings.ToLower(builder),
+	}
+	return postInfo[int](ctx, *api.apiClient, request)
+}
+
+func (api *InfoApiDefault) GetMktPx(ctx context.Context, coin string) (float64, error) {
+	mids, err := api.GetAllMids(ctx)
+	if err != nil {
+		return 0, err
+	}
+	mid, ok := mids[coin]
+	if !ok {
+		return 0, &UnknownAssetError{Coin: coin}
+	}
+	parsed, err := strconv.ParseFloat(mid, 64)
+	if err != nil {
+		return 0, &DecodeError{Body: []byte(mid), Err: err}
+	}
+	return parsed, nil
+}
+
+func (api *InfoApiDefault) GetMarkPx(ctx context.Context, coin string) (float64, error) {
+	assetCtx, err := api.getAssetCtx(ctx, coin)
+	if err != nil {
+		return 0, err
+	}
+	parsed, err := assetCtx.MarkPrice()
+	if err != nil {
+		return 0, &DecodeError{Body: []byte(assetCtx.MarkPx), Err: err}
+	}
+	return parsed, nil
+}
+
+func (api *InfoApiDefault) GetOraclePx(ctx context.Context, coin string) (float64, error) {
+	assetCtx, err := api.getAssetCtx(ctx, coin)
+	if err != nil {
+		return 0, err
+	}
+	parsed, err := assetCtx.OraclePrice()
+	if err != nil {
+		return 0, &DecodeError{Body: []byte(assetCtx.OraclePx), Err: err}
+	}
+	return parsed, nil
 }
 
-func (api *InfoApiDefault) GetMktPx(ctx context.Context, coin string) float64 {
-	parsed, _ := strconv.ParseFloat(api.GetAllMids(ctx)[coin], 32)
-	return parsed
+func (api *InfoApiDefault) getAssetCtx(ctx context.Context, coin string) (AssetCtx, error) {
+	metaAndCtxs, err := api.GetMetaAndAssetCtxs(ctx)
+	if err != nil {
+		return AssetCtx{}, err
+	}
+	_, assetCtx, ok := metaAndCtxs.Asset(coin)
+	if !ok {
+		return AssetCtx{}, &UnknownAssetError{Coin: coin}
+	}
+	return assetCtx, nil
 }
 
-func (api *InfoApiDefault) GetUserFills(ctx context.Context, address string) []OrderFill {
+func (api *InfoApiDefault) GetUserFills(ctx context.Context, address string) ([]OrderFill, error) {
 	request := GetInfoRequest{
 		User:  &address,
 		Typez: "userFills",
 	}
-	anyResult := (*api.apiClient).Post(ctx, "/info", request)
-	parsed, _ := json.Marshal(anyResult)
-	var result []OrderFill
-	_ = json.Unmarshal(parsed, &result)
-	return result
+	return postInfo[[]OrderFill](ctx, *api.apiClient, request)
+}
+
+// GetUserFillsByTime returns at most 2000 fills between start and end, in milliseconds, starting from the
+// oldest. Use UserFillsIterator to walk ranges with more fills.
+func (api *InfoApiDefault) GetUserFillsByTime(ctx context.Context, address string, start int64, end int64, aggregateByTime bool) ([]OrderFill, error) {
+	request := GetUserFillsByTimeRequest{
+		User:            address,
+		Typez:           "userFillsByTime",
+		StartTime:       start,
+		EndTime:         end,
+		AggregateByTime: aggregateByTime,
+	}
+	return postInfo[[]OrderFill](ctx, *api.apiClient, request)
 }
 
-func (api *InfoApiDefault) GetNonFundingUpdates(ctx context.Context, address string) []NonFundingUpdate {
+func (api *InfoApiDefault) GetNonFundingUpdates(ctx context.Context, address string) ([]NonFundingUpdate, error) {
 	request := GetInfoRequest{
 		User:  &address,
 		Typez: "userNonFundingLedgerUpdates",
 	}
-	anyResult := (*api.apiClient).Post(ctx, "/info", request)
-	parsed, _ := json.Marshal(anyResult)
-	var result []NonFundingUpdate
-	_ = json.Unmarshal(parsed, &result)
-	return result
+	return postInfo[[]NonFundingUpdate](ctx, *api.apiClient, request)
 }
 
-func (api *InfoApiDefault) GetFundingUpdates(ctx context.Context, address string) []FundingUpdate {
+func (api *InfoApiDefault) GetFundingUpdates(ctx context.Context, address string) ([]FundingUpdate, error) {
 	request := GetInfoRequest{
 		User:  &address,
 		Typez: "userFunding",
 	}
-	anyResult := (*api.apiClient).Post(ctx, "/info", request)
-	parsed, _ := json.Marshal(anyResult)
-	var result []FundingUpdate
-	_ = json.Unmarshal(parsed, &result)
-	return result
+	return postInfo[[]FundingUpdate](ctx, *api.apiClient, request)
+}
+
+type Candle struct {
+	OpenTime  int64  `json:"t"`
+	CloseTime int64  `json:"T"`
+	Coin      string `json:"s"`
+	Interval  string `json:"i"`
+	Open      string `json:"o"`
+	Close     string `json:"c"`
+	High      string `json:"h"`
+	Low       string `json:"l"`
+	Volume    string `json:"v"`
+	Trades    int    `json:"n"`
+}
+
+type FundingRate struct {
+	Coin        string `json:"coin"`
+	FundingRate string `json:"fundingRate"`
+	Premium     string `json:"premium"`
+	Time        int64  `json:"time"`
+}
+
+// GetCandles returns the candles opened between start and end, in milliseconds. interval is one of "1m", "3m",
+// "5m", "15m", "30m", "1h", "2h", "4h", "8h", "12h", "1d", "3d", "1w" or "1M". Only the most recent 5000
+// candles of each interval are kept by the server, ranges spanning more are fetched page by page.
+func (api *InfoApiDefault) GetCandles(ctx context.Context, coin string, interval string, start int64, end int64) ([]Candle, error) {
+	fetch := func(start int64) ([]Candle, error) {
+		request := GetCandlesRequest{
+			Typez: "candleSnapshot",
+			Req: CandleSnapshotRange{
+				Coin:      coin,
+				Interval:  interval,
+				StartTime: start,
+				EndTime:   end,
+			},
+		}
+		return postInfo[[]Candle](ctx, *api.apiClient, request)
+	}
+	next := func(candle Candle) int64 {
+		return candle.CloseTime + 1
+	}
+	return paginate(start, end, fetch, next)
+}
+
+// GetFundingHistory returns the funding rates of a coin between start and end, in milliseconds,
+// fetching page by page as the server returns at most 500 rates per request
+func (api *InfoApiDefault) GetFundingHistory(ctx context.Context, coin string, start int64, end int64) ([]FundingRate, error) {
+	fetch := func(start int64) ([]FundingRate, error) {
+		request := GetFundingHistoryRequest{
+			Typez:     "fundingHistory",
+			Coin:      coin,
+			StartTime: start,
+			EndTime:   end,
+		}
+		return postInfo[[]FundingRate](ctx, *api.apiClient, request)
+	}
+	next := func(rate FundingRate) int64 {
+		return rate.Time + 1
+	}
+	return paginate(start, end, fetch, next)
 }
 
-func (api *InfoApiDefault) GetWithdrawals(ctx context.Context, address string) []Withdrawal {
+// paginate calls fetch from start until end is reached or no more items are returned.
+// next returns the start of the page following an item, items are expected in ascending time.
+func paginate[T any](start int64, end int64, fetch func(start int64) ([]T, error), next func(T) int64) ([]T, error) {
+	var result []T
+	for start <= end {
+		page, err := fetch(start)
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		result = append(result, page...)
+
+		pageEnd := next(page[len(page)-1])
+		if pageEnd <= start {
+			break
+		}
+		start = pageEnd
+	}
+	return result, nil
+}
+
+func (api *InfoApiDefault) GetWithdrawals(ctx context.Context, address string) ([]Withdrawal, error) {
 	var ws []Withdrawal
-	ups := api.GetNonFundingUpdates(ctx, address)
+	ups, err := api.GetNonFundingUpdates(ctx, address)
+	if err != nil {
+		return nil, err
+	}
 	for _, up := range ups {
 		if up.Delta.Type == "withdraw" {
 			w := Withdrawal{
@@ -214,5 +619,5 @@ func (api *InfoApiDefault) GetWithdrawals(ctx context.Context, address string) [
 			ws = append(ws, w)
 		}
 	}
-	return ws
+	return ws, nil
 }
@@ -0,0 +1,387 @@
+package hyperliquid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MultiSigTransaction is an action of a multi-sig user waiting for the signatures of its authorized users.
+// It is serialized to JSON to collect the signatures on other machines: each signer unmarshals it, calls Sign
+// and sends it back, then the outer signer submits it with SendMultiSig.
+type MultiSigTransaction struct {
+	MultiSigUser string          `json:"multiSigUser"`
+	OuterSigner  string          `json:"outerSigner"`
+	Action       json.RawMessage `json:"action"`
+	Nonce        int64           `json:"nonce"`
+	VaultAddress *string         `json:"vaultAddress,omitempty"`
+	ExpiresAfter *int64          `json:"expiresAfter,omitempty"`
+	IsMainnet    bool            `json:"isMainnet"`
+	Signatures   []RsvSignature  `json:"signatures"`
+}
+
+// MultiSigAction is the envelope of an action sent for a multi-sig user, it is hashed from its JSON
+// so the inner action keeps the field order it was signed with
+type MultiSigAction struct {
+	Type             string          `json:"type"`
+	SignatureChainId string          `json:"signatureChainId"`
+	Signatures       []RsvSignature  `json:"signatures"`
+	Payload          MultiSigPayload `json:"payload"`
+}
+
+type MultiSigPayload struct {
+	MultiSigUser string          `json:"multiSigUser"`
+	OuterSigner  string          `json:"outerSigner"`
+	Action       json.RawMessage `json:"action"`
+}
+
+type sendMultiSigEnvelope struct {
+	Type               string `json:"type"`
+	HLChain            string `json:"hyperliquidChain"`
+	MultiSigActionHash string `json:"multiSigActionHash"`
+	Nonce              int64  `json:"nonce"`
+}
+
+type ConvertToMultiSigUserAction struct {
+	Type             string `msgpack:"type" json:"type"`
+	SignatureChainId string `msgpack:"signatureChainId" json:"signatureChainId"`
+	HLChain          string `msgpack:"hyperliquidChain" json:"hyperliquidChain"`
+	Signers          string `msgpack:"signers" json:"signers"`
+	Nonce            int64  `msgpack:"nonce" json:"nonce"`
+}
+
+// NewMultiSigTransaction wraps an L1 or user signed action for multiSigUser, which outerSigner will submit.
+// User signed actions must use nonce as their time or nonce field, their chain is set from the network.
+// The exchange WithExpiry default is not applied since signatures are collected over an unknown time,
+// an expiry is only set WithExpiresAfter.
+func (e *ExchangeImpl) NewMultiSigTransaction(multiSigUser string, outerSigner string, action any, nonce int64, opts ...ActionOption) (*MultiSigTransaction, error) {
+	data, err := json.Marshal(e.withChain(action))
+	if err != nil {
+		return nil, err
+	}
+	options := buildActionOptions(opts)
+
+	return &MultiSigTransaction{
+		MultiSigUser: strings.ToLower(multiSigUser),
+		OuterSigner:  strings.ToLower(outerSigner),
+		Action:       data,
+		Nonce:        nonce,
+		VaultAddress: options.vaultAddress,
+		ExpiresAfter: options.expiresAfter,
+		IsMainnet:    (*e.cli).IsMainnet(),
+	}, nil
+}
+
+// Sign adds the signature of an authorized user of the multi-sig user, whose key is returned by manager
+func (tx *MultiSigTransaction) Sign(address string, manager KeyManager) error {
+	req, err := tx.sigRequest()
+	if err != nil {
+		return &SigningError{Address: address, Err: err}
+	}
+
+	v, r, s, err := NewSigner(&manager).Sign(address, req)
+	if err != nil {
+		return &SigningError{Address: address, Err: err}
+	}
+
+	tx.AddSignature(ToTypedSig(r, s, v))
+	return nil
+}
+
+// AddSignature adds a signature made outside of the SDK, e.g. by a hardware wallet
+func (tx *MultiSigTransaction) AddSignature(signature RsvSignature) {
+	tx.Signatures = append(tx.Signatures, trimSignature(signature))
+}
+
+// sigRequest is what authorized users sign: user signed actions get the multi-sig user and outer signer
+// added to their type, L1 actions are hashed in a [multiSigUser, outerSigner, action] envelope
+func (tx *MultiSigTransaction) sigRequest() (SigRequest, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(tx.Action, &header); err != nil {
+		return SigRequest{}, err
+	}
+
+	if _, ok := userSignedTypes[header.Type]; ok {
+		t, message, err := userSignedMessage(tx.Action, tx.IsMainnet)
+		if err != nil {
+			return SigRequest{}, err
+		}
+		message["payloadMultiSigUser"] = tx.MultiSigUser
+		message["outerSigner"] = tx.OuterSigner
+
+		fields := []apitypes.Type{
+			t.Fields[0],
+			{Name: "payloadMultiSigUser", Type: "address"},
+			{Name: "outerSigner", Type: "address"},
+		}
+		fields = append(fields, t.Fields[1:]...)
+
+		return SigRequest{
+			PrimaryType: t.PrimaryType,
+			DType:       fields,
+			DTypeMsg:    message,
+			IsMainNet:   tx.IsMainnet,
+		}, nil
+	}
+
+	envelope, err := json.Marshal([]any{tx.MultiSigUser, tx.OuterSigner, tx.Action})
+	if err != nil {
+		return SigRequest{}, err
+	}
+	packed, err := packJSON(envelope)
+	if err != nil {
+		return SigRequest{}, err
+	}
+	hash := hashPackedAction(packed, tx.vault(), tx.Nonce, tx.ExpiresAfter)
+
+	return agentSigRequest(buildMessage(hash.Bytes(), tx.IsMainnet), tx.IsMainnet), nil
+}
+
+func (tx *MultiSigTransaction) vault() string {
+	if tx.VaultAddress == nil {
+		return ""
+	}
+	return *tx.VaultAddress
+}
+
+func (tx *MultiSigTransaction) multiSigAction() MultiSigAction {
+	_, chainId := UserSignedChain(tx.IsMainnet)
+	return MultiSigAction{
+		Type:             "multiSig",
+		SignatureChainId: chainId,
+		Signatures:       tx.Signatures,
+		Payload: MultiSigPayload{
+			MultiSigUser: tx.MultiSigUser,
+			OuterSigner:  tx.OuterSigner,
+			Action:       tx.Action,
+		},
+	}
+}
+
+// hash is the multiSigActionHash signed by the outer signer, the hash of the envelope without its type
+func (tx *MultiSigTransaction) hash() (common.Hash, error) {
+	action := tx.multiSigAction()
+	data, err := json.Marshal(struct {
+		SignatureChainId string          `json:"signatureChainId"`
+		Signatures       []RsvSignature  `json:"signatures"`
+		Payload          MultiSigPayload `json:"payload"`
+	}{action.SignatureChainId, action.Signatures, action.Payload})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	packed, err := packJSON(data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return hashPackedAction(packed, tx.vault(), tx.Nonce, tx.ExpiresAfter), nil
+}
+
+// SendMultiSig signs a multi-sig transaction with the key of its outer signer and submits it, the response
+// is the one of the inner action, e.g. order statuses for an order
+func (e *ExchangeImpl) SendMultiSig(ctx context.Context, tx *MultiSigTransaction) (json.RawMessage, error) {
+	if len(tx.Signatures) == 0 {
+		return nil, errors.New("multi-sig transaction has no signatures")
+	}
+	if tx.IsMainnet != (*e.cli).IsMainnet() {
+		return nil, fmt.Errorf("multi-sig transaction for mainnet %t sent to mainnet %t", tx.IsMainnet, (*e.cli).IsMainnet())
+	}
+
+	hash, err := tx.hash()
+	if err != nil {
+		return nil, &SigningError{Address: tx.OuterSigner, Err: err}
+	}
+	envelope := e.withChain(sendMultiSigEnvelope{
+		Type:               "sendMultiSig",
+		MultiSigActionHash: hash.Hex(),
+		Nonce:              tx.Nonce,
+	})
+
+	v, r, s, err := e.SignUserSignedAction(ctx, tx.OuterSigner, envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := ExchangeRequest{
+		Action:       tx.multiSigAction(),
+		Nonce:        tx.Nonce,
+		Signature:    ToTypedSig(r, s, v),
+		VaultAddress: tx.VaultAddress,
+		ExpiresAfter: tx.ExpiresAfter,
+	}
+
+	res, err := (*e.cli).Post(ctx, "/exchange", payload)
+	if err != nil {
+		return nil, err
+	}
+	e.logger.LogInfo(ctx, fmt.Sprintf("Response is %s", res))
+
+	var inner json.RawMessage
+	status, responseErr, err := unmarshalInnerResponse(res, &inner)
+	if err != nil {
+		return nil, &DecodeError{Body: res, Err: err}
+	}
+	if responseErr != nil {
+		return nil, &ExchangeError{Status: status, Message: *responseErr}
+	}
+
+	return res, nil
+}
+
+// ConvertToMultiSigUser makes address a multi-sig user whose actions need the signatures of threshold of
+// authorizedUsers. Converting back to a normal user is done by the multi-sig user with no authorized users.
+func (e *ExchangeImpl) ConvertToMultiSigUser(ctx context.Context, address string, authorizedUsers []string, threshold int) (*TransferResponse, error) {
+	signers := "null"
+	if len(authorizedUsers) > 0 {
+		users := make([]string, len(authorizedUsers))
+		for i, user := range authorizedUsers {
+			users[i] = strings.ToLower(user)
+		}
+		sort.Strings(users)
+
+		data, err := json.Marshal(struct {
+			AuthorizedUsers []string `json:"authorizedUsers"`
+			Threshold       int      `json:"threshold"`
+		}{users, threshold})
+		if err != nil {
+			return nil, err
+		}
+		signers = string(data)
+	}
+
+	timestamp, err := e.nonceFor(address)
+	if err != nil {
+		return nil, err
+	}
+
+	action := ConvertToMultiSigUserAction{
+		Type:    "convertToMultiSigUser",
+		Signers: signers,
+		Nonce:   timestamp,
+	}
+
+	return e.postUserSignedAction(ctx, address, action, timestamp)
+}
+
+// trimSignature removes the leading zeros of r and s, the form used by the exchange to hash signatures
+func trimSignature(signature RsvSignature) RsvSignature {
+	signature.R = hexutil.EncodeBig(new(big.Int).SetBytes(common.FromHex(signature.R)))
+	signature.S = hexutil.EncodeBig(new(big.Int).SetBytes(common.FromHex(signature.S)))
+	return signature
+}
+
+// packJSON packs a JSON document with msgpack keeping the order of object keys, which msgpack would lose
+// when packing the document decoded into a map
+func packJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := packOrdered(msgpack.NewEncoder(&buf), value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type orderedEntry struct {
+	key   string
+	value any
+}
+
+type orderedObject []orderedEntry
+
+func decodeOrdered(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		var object orderedObject
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, orderedEntry{key: key.(string), value: value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := []any{}
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	default:
+		return token, nil
+	}
+}
+
+func packOrdered(enc *msgpack.Encoder, value any) error {
+	switch v := value.(type) {
+	case orderedObject:
+		if err := enc.EncodeMapLen(len(v)); err != nil {
+			return err
+		}
+		for _, entry := range v {
+			if err := enc.EncodeString(entry.key); err != nil {
+				return err
+			}
+			if err := packOrdered(enc, entry.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if err := enc.EncodeArrayLen(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := packOrdered(enc, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return enc.EncodeInt(n)
+		}
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return enc.EncodeUint(n)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return enc.EncodeFloat64(f)
+	default:
+		return enc.Encode(v)
+	}
+}
@@ -0,0 +1,158 @@
+package hyperliquid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NonceSource returns the nonces of signed actions. The exchange keeps the 100 highest nonces of each signer
+// and rejects a nonce lower than all of them or already used, so Next must never return the same nonce twice
+// for a signer. Nonces must also be within a day of the exchange time, which is why they are timestamps.
+type NonceSource interface {
+	Next(signer string) (int64, error)
+}
+
+// MemoryNonceSource returns increasing timestamps for each signer, it is shared by the exchanges of a process
+// unless they are built WithNonceSource
+type MemoryNonceSource struct {
+	mu   sync.Mutex
+	last map[string]int64
+}
+
+func NewMemoryNonceSource() *MemoryNonceSource {
+	return &MemoryNonceSource{last: make(map[string]int64)}
+}
+
+func (s *MemoryNonceSource) Next(signer string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	signer = strings.ToLower(signer)
+	nonce := nextNonce(s.last[signer])
+	s.last[signer] = nonce
+	return nonce, nil
+}
+
+var defaultNonceSource = NewMemoryNonceSource()
+
+// FileNonceSource stores the last nonce of each signer in a file, so processes sharing the file never reuse a nonce,
+// and a restart or a clock going backwards continues after the last one. Updates hold a lock on a ".lock" file next
+// to it and replace the file atomically, so a crash never leaves it empty or partially written.
+type FileNonceSource struct {
+	path string
+}
+
+// NewFileNonceSource returns a source storing its nonces at path. File locks are only supported on Linux, macOS
+// and the BSDs, on other platforms, e.g. Windows, it returns an error.
+func NewFileNonceSource(path string) (*FileNonceSource, error) {
+	s := &FileNonceSource{path: path}
+	lock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer s.unlock(lock)
+
+	// fail on a corrupted file now rather than on the first action
+	if _, err := s.read(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileNonceSource) Next(signer string) (int64, error) {
+	lock, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer s.unlock(lock)
+
+	last, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+
+	signer = strings.ToLower(signer)
+	nonce := nextNonce(last[signer])
+	last[signer] = nonce
+
+	if err := s.write(last); err != nil {
+		return 0, err
+	}
+	return nonce, nil
+}
+
+func (s *FileNonceSource) lock() (*os.File, error) {
+	f, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock nonce file %s: %w", s.path, err)
+	}
+	return f, nil
+}
+
+func (s *FileNonceSource) unlock(f *os.File) {
+	_ = unlockFile(f)
+	_ = f.Close()
+}
+
+// read returns the last nonces of the signers, which are empty when the file doesn't exist yet
+func (s *FileNonceSource) read() (map[string]int64, error) {
+	last := make(map[string]int64)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return last, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &last); err != nil {
+		return nil, fmt.Errorf("failed to parse nonce file %s: %w", s.path, err)
+	}
+	return last, nil
+}
+
+// write replaces the file with a synced temporary one, the rename is atomic
+func (s *FileNonceSource) write(last map[string]int64) error {
+	data, err := json.Marshal(last)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// nextNonce is the current timestamp, or the nonce following last when the clock is behind it
+func nextNonce(last int64) int64 {
+	nonce := time.Now().UnixMilli()
+	if nonce <= last {
+		return last + 1
+	}
+	return nonce
+}
@@ -0,0 +1,161 @@
+package hyperliquid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// RequestOutcome is what happened to a request whose response was lost
+type RequestOutcome string
+
+// RequestExecuted is the outcome of a request whose orders were found by cloid
+const RequestExecuted RequestOutcome = "executed"
+
+// RequestInvalidated is the outcome of a request whose nonce was used by a noop, it will never be executed
+// and can be retried with a new nonce
+const RequestInvalidated RequestOutcome = "invalidated"
+
+// RequestNonceUsed is the outcome of a request whose nonce can't be used by a noop, either because the
+// request was executed or because the nonce expired. It must not be retried blindly.
+const RequestNonceUsed RequestOutcome = "nonceUsed"
+
+// Resolution is the result of ResolveRequest
+type Resolution struct {
+	Outcome RequestOutcome
+	// Orders are the orders of the request found by cloid when Outcome is RequestExecuted
+	Orders []OrderResponse
+	// Message is the reason the noop was rejected when Outcome is RequestNonceUsed
+	Message string
+}
+
+// Noop sends an action doing nothing with the given nonce, so no other action signed with it can be executed.
+// Like other L1 actions it is signed by the agent of address when there is one, so it only invalidates the
+// nonces of the agent, not the ones of user signed actions which ResolveRequest handles.
+func (e *ExchangeImpl) Noop(ctx context.Context, address string, nonce int64, opts ...ActionOption) (*ActionResponse, error) {
+	return e.noop(ctx, e.agentOf(address), nonce, buildActionOptions(opts))
+}
+
+func (e *ExchangeImpl) noop(ctx context.Context, signer string, nonce int64, options actionOptions) (*ActionResponse, error) {
+	action := NoopAction{Type: "noop"}
+
+	res, err := e.postL1ActionAs(ctx, signer, action, options, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.parseActionResponse(ctx, res)
+}
+
+// ResolveRequest finds out whether an action signed for address, whose response was lost e.g. on a timeout,
+// was executed. Orders with a cloid are looked up first, otherwise the nonce of the request is invalidated
+// with a noop so it can't be executed later. The noop is signed by the signer of the request: the agent of
+// address for L1 actions, address itself for user signed actions, whose key must then be in the KeyManager.
+// When an error is returned the noop itself may have been executed, so calling ResolveRequest again may
+// report RequestNonceUsed for a request which wasn't executed.
+func (e *ExchangeImpl) ResolveRequest(ctx context.Context, address string, request ExchangeRequest) (*Resolution, error) {
+	actionType, err := requestActionType(request.Action)
+	if err != nil {
+		return nil, err
+	}
+	signer := e.agentOf(address)
+	if _, ok := userSignedTypes[actionType]; ok {
+		signer = address
+	}
+
+	user := address
+	var options actionOptions
+	if request.VaultAddress != nil {
+		user = *request.VaultAddress
+		options.vaultAddress = request.VaultAddress
+	}
+	cloids := requestCloids(request.Action)
+
+	orders, err := e.findOrders(ctx, user, cloids)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) > 0 {
+		return &Resolution{Outcome: RequestExecuted, Orders: orders}, nil
+	}
+
+	_, err = e.noop(ctx, signer, request.Nonce, options)
+	var exchangeErr *ExchangeError
+	if errors.As(err, &exchangeErr) {
+		// the request may have been executed between the lookup and the noop
+		orders, err := e.findOrders(ctx, user, cloids)
+		if err != nil {
+			return nil, err
+		}
+		if len(orders) > 0 {
+			return &Resolution{Outcome: RequestExecuted, Orders: orders}, nil
+		}
+		return &Resolution{Outcome: RequestNonceUsed, Message: exchangeErr.Message}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Resolution{Outcome: RequestInvalidated}, nil
+}
+
+// findOrders returns the orders found for cloids, orders which don't exist are skipped
+func (e *ExchangeImpl) findOrders(ctx context.Context, user string, cloids []string) ([]OrderResponse, error) {
+	var orders []OrderResponse
+	for _, cloid := range cloids {
+		order, err := e.infoApi.FindOrder(ctx, user, cloid)
+		if err != nil {
+			return nil, err
+		}
+		if order.Status == "order" {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+// requestActionType returns the type of an action, whether it is a struct or was decoded from JSON
+func requestActionType(action any) (string, error) {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return "", err
+	}
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return "", err
+	}
+	return header.Type, nil
+}
+
+// requestCloids returns the cloids of the orders placed or modified by an action
+func requestCloids(action any) []string {
+	var orders []OrderWire
+	switch a := action.(type) {
+	case PlaceOrderAction:
+		orders = a.Orders
+	case *PlaceOrderAction:
+		orders = a.Orders
+	case ModifyOrdersAction:
+		orders = modifiedOrders(a.Orders)
+	case *ModifyOrdersAction:
+		orders = modifiedOrders(a.Orders)
+	}
+
+	var cloids []string
+	for _, order := range orders {
+		if order.Cloid != nil {
+			cloids = append(cloids, *order.Cloid)
+		}
+	}
+	return cloids
+}
+
+func modifiedOrders(modifies []ModifyOrderWire) []OrderWire {
+	orders := make([]OrderWire, 0, len(modifies))
+	for _, modify := range modifies {
+		orders = append(orders, modify.Order)
+	}
+	return orders
+}
@@ -0,0 +1,109 @@
+package hyperliquid
+
+import (
+	"strings"
+	"time"
+)
+
+// ExchangeOption customizes the exchange built by NewExchange
+type ExchangeOption func(*ExchangeImpl)
+
+// WithAgent signs the L1 actions of masterAddress, like orders and cancels, with the key of an agent approved
+// with ApproveAgent. The KeyManager must return the agent key for agentAddress, the master key isn't needed.
+func WithAgent(masterAddress string, agentAddress string) ExchangeOption {
+	return func(e *ExchangeImpl) {
+		if e.agents == nil {
+			e.agents = make(map[string]string)
+		}
+		e.agents[strings.ToLower(masterAddress)] = agentAddress
+	}
+}
+
+// WithNonceSource takes the nonces of signed actions from source instead of the process wide MemoryNonceSource,
+// e.g. a FileNonceSource shared by processes signing with the same keys
+func WithNonceSource(source NonceSource) ExchangeOption {
+	return func(e *ExchangeImpl) {
+		e.nonces = source
+	}
+}
+
+// WithExpiry rejects the L1 actions reaching the exchange later than ttl after they are signed, e.g. orders
+// delayed in a queue. User signed actions, like withdrawals and sends, don't expire.
+func WithExpiry(ttl time.Duration) ExchangeOption {
+	return func(e *ExchangeImpl) {
+		e.expiry = ttl
+	}
+}
+
+// ActionOption customizes a single exchange action
+type ActionOption func(*actionOptions)
+
+type actionOptions struct {
+	vaultAddress *string
+	builder      *BuilderWire
+	expiresAfter *int64
+	priceSource  PriceSource
+}
+
+// WithVaultAddress makes the action act on behalf of a vault or sub-account, the signer must be its leader or master
+func WithVaultAddress(address string) ActionOption {
+	return func(o *actionOptions) {
+		o.vaultAddress = &address
+	}
+}
+
+// WithBuilder adds a builder code to orders, fee is in tenths of a basis point and the user must have approved
+// at least that fee for the builder with ApproveBuilderFee. Actions other than orders ignore it.
+func WithBuilder(address string, fee int) ActionOption {
+	return func(o *actionOptions) {
+		o.builder = &BuilderWire{Address: strings.ToLower(address), Fee: fee}
+	}
+}
+
+// WithExpiresAfter makes the exchange reject the action if it arrives after at, instead of the exchange default expiry.
+// User signed actions ignore it.
+func WithExpiresAfter(at time.Time) ActionOption {
+	return func(o *actionOptions) {
+		ms := at.UnixMilli()
+		o.expiresAfter = &ms
+	}
+}
+
+// PriceSource is the price market orders apply their slippage to when they have no price
+type PriceSource string
+
+const PriceSourceMid PriceSource = "mid"
+const PriceSourceMark PriceSource = "mark"
+const PriceSourceOracle PriceSource = "oracle"
+
+// WithPriceSource makes MarketOpen, MarketClose and Trigger apply their slippage to the mark or oracle price
+// instead of the mid. Mark and oracle prices are only known for perps.
+func WithPriceSource(source PriceSource) ActionOption {
+	return func(o *actionOptions) {
+		o.priceSource = source
+	}
+}
+
+func buildActionOptions(opts []ActionOption) actionOptions {
+	var options actionOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// vault returns the address used in the action hash, which is empty when no vault is set
+func (o actionOptions) vault() string {
+	if o.vaultAddress == nil {
+		return ""
+	}
+	return *o.vaultAddress
+}
+
+// account returns the address holding the positions the action acts on
+func (o actionOptions) account(address string) string {
+	if o.vaultAddress == nil {
+		return address
+	}
+	return *o.vaultAddress
+}
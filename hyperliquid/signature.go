@@ -1,6 +1,8 @@
 package hyperliquid
 
 import (
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
@@ -34,6 +36,9 @@ func (signer Signer) Sign(address string, req SigRequest) (byte, [32]byte, [32]b
 	}
 
 	key := (*signer.manager).GetKey(address)
+	if key == nil {
+		return 0, [32]byte{}, [32]byte{}, fmt.Errorf("no key found for address %s", address)
+	}
 
 	bytes, _, err := apitypes.TypedDataAndHash(typedData)
 
@@ -0,0 +1,101 @@
+package hyperliquid
+
+import (
+	"context"
+	"strings"
+)
+
+// Delegation is the stake of a user with a validator, it can't be undelegated before LockedUntilTimestamp
+type Delegation struct {
+	Validator            string `json:"validator"`
+	Amount               string `json:"amount"`
+	LockedUntilTimestamp int64  `json:"lockedUntilTimestamp"`
+}
+
+type DelegatorSummary struct {
+	Delegated              string `json:"delegated"`
+	Undelegated            string `json:"undelegated"`
+	TotalPendingWithdrawal string `json:"totalPendingWithdrawal"`
+	NPendingWithdrawals    int    `json:"nPendingWithdrawals"`
+}
+
+// DelegatorReward is a staking reward, Source is "delegation" or "commission"
+type DelegatorReward struct {
+	Time        int64  `json:"time"`
+	Source      string `json:"source"`
+	TotalAmount string `json:"totalAmount"`
+}
+
+// ValidatorSummary describes a validator, Stake is in wei and Stats maps periods like "day", "week" and "month"
+// to the validator performance in that period
+type ValidatorSummary struct {
+	Validator       string         `json:"validator"`
+	Signer          string         `json:"signer"`
+	Name            string         `json:"name"`
+	Description     string         `json:"description"`
+	NRecentBlocks   int            `json:"nRecentBlocks"`
+	Stake           uint64         `json:"stake"`
+	IsJailed        bool           `json:"isJailed"`
+	UnjailableAfter *int64         `json:"unjailableAfter"`
+	IsActive        bool           `json:"isActive"`
+	Commission      string         `json:"commission"`
+	Stats           ValidatorStats `json:"stats"`
+}
+
+type ValidatorStats map[string]ValidatorPeriodStats
+
+type ValidatorPeriodStats struct {
+	UptimeFraction string `json:"uptimeFraction"`
+	PredictedApr   string `json:"predictedApr"`
+	NSamples       int    `json:"nSamples"`
+}
+
+// UnmarshalJSON reads the [[period, stats], ...] array returned by the API
+func (s *ValidatorStats) UnmarshalJSON(data []byte) error {
+	periods, err := unmarshalPeriods[ValidatorPeriodStats](data)
+	*s = periods
+	return err
+}
+
+// TokenDelegate delegates wei of HYPE from the staking balance to a validator, or undelegates it
+func (e *ExchangeImpl) TokenDelegate(ctx context.Context, address string, validator string, wei uint64, isUndelegate bool) (*TransferResponse, error) {
+	timestamp, err := e.nonceFor(address)
+	if err != nil {
+		return nil, err
+	}
+
+	action := TokenDelegateAction{
+		Type:         "tokenDelegate",
+		Validator:    strings.ToLower(validator),
+		Wei:          wei,
+		IsUndelegate: isUndelegate,
+		Nonce:        timestamp,
+	}
+
+	return e.postUserSignedAction(ctx, address, action, timestamp)
+}
+
+// CDeposit moves wei of HYPE from the spot balance to the staking balance
+func (e *ExchangeImpl) CDeposit(ctx context.Context, address string, wei uint64) (*TransferResponse, error) {
+	return e.stakingTransfer(ctx, address, "cDeposit", wei)
+}
+
+// CWithdraw moves wei of HYPE from the staking balance to the spot balance, it is available after the unstaking queue
+func (e *ExchangeImpl) CWithdraw(ctx context.Context, address string, wei uint64) (*TransferResponse, error) {
+	return e.stakingTransfer(ctx, address, "cWithdraw", wei)
+}
+
+func (e *ExchangeImpl) stakingTransfer(ctx context.Context, address string, actionType string, wei uint64) (*TransferResponse, error) {
+	timestamp, err := e.nonceFor(address)
+	if err != nil {
+		return nil, err
+	}
+
+	action := StakingTransferAction{
+		Type:  actionType,
+		Wei:   wei,
+		Nonce: timestamp,
+	}
+
+	return e.postUserSignedAction(ctx, address, action, timestamp)
+}
@@ -0,0 +1,50 @@
+package hyperliquid
+
+import (
+	"context"
+	"strings"
+)
+
+// CreateSubAccount creates a sub-account of address and returns the sub-account address
+func (e *ExchangeImpl) CreateSubAccount(ctx context.Context, address string, name string) (string, error) {
+	action := CreateSubAccountAction{
+		Type: "createSubAccount",
+		Name: name,
+	}
+
+	res, err := e.postL1Action(ctx, address, action, actionOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	var inner struct {
+		Type string `json:"type"`
+		Data string `json:"data"`
+	}
+	status, responseErr, err := unmarshalInnerResponse(res, &inner)
+	if err != nil {
+		e.logger.LogErr(ctx, "failed to unmarshal createSubAccount response", err)
+		return "", &DecodeError{Body: res, Err: err}
+	}
+	if responseErr != nil {
+		return "", &ExchangeError{Status: status, Message: *responseErr}
+	}
+
+	return inner.Data, nil
+}
+
+// SubAccountModify renames a sub-account of address
+func (e *ExchangeImpl) SubAccountModify(ctx context.Context, address string, subAccount string, name string) (*ActionResponse, error) {
+	action := SubAccountModifyAction{
+		Type:           "subAccountModify",
+		SubAccountUser: strings.ToLower(subAccount),
+		Name:           name,
+	}
+
+	res, err := e.postL1Action(ctx, address, action, actionOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.parseActionResponse(ctx, res)
+}
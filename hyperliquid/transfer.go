@@ -0,0 +1,115 @@
+package hyperliquid
+
+import (
+	"context"
+	"math"
+	"strings"
+)
+
+// UsdSend sends USDC from the perp balance to another address
+func (e *ExchangeImpl) UsdSend(ctx context.Context, request UsdSendRequest) (*TransferResponse, error) {
+	timestamp, err := e.nonceFor(request.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	action := UsdSendAction{
+		Type:        "usdSend",
+		Destination: strings.ToLower(request.Destination),
+		Amount:      usdToWire(request.Amount),
+		Time:        timestamp,
+	}
+
+	return e.postUserSignedAction(ctx, request.Address, action, timestamp)
+}
+
+// SpotSend sends a spot token to another address, the amount is rounded to the wei decimals of the token
+func (e *ExchangeImpl) SpotSend(ctx context.Context, request SpotSendRequest) (*TransferResponse, error) {
+	token, err := e.spotToken(ctx, request.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := e.nonceFor(request.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	action := SpotSendAction{
+		Type:        "spotSend",
+		Destination: strings.ToLower(request.Destination),
+		Token:       request.Token,
+		Amount:      amountToWire(request.Amount, token.WeiDecimals),
+		Time:        timestamp,
+	}
+
+	return e.postUserSignedAction(ctx, request.Address, action, timestamp)
+}
+
+// UsdClassTransfer moves USDC between the spot and perp balances, with WithVaultAddress it moves the
+// balances of a sub-account
+func (e *ExchangeImpl) UsdClassTransfer(ctx context.Context, request UsdClassTransferRequest, opts ...ActionOption) (*TransferResponse, error) {
+	timestamp, err := e.nonceFor(request.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := usdToWire(request.Amount)
+	if options := buildActionOptions(opts); options.vaultAddress != nil {
+		amount += " subaccount:" + *options.vaultAddress
+	}
+
+	action := UsdClassTransferAction{
+		Type:   "usdClassTransfer",
+		Amount: amount,
+		ToPerp: request.ToPerp,
+		Nonce:  timestamp,
+	}
+
+	return e.postUserSignedAction(ctx, request.Address, action, timestamp)
+}
+
+// SubAccountTransfer moves USDC between the perp balances of the master account and one of its sub-accounts
+func (e *ExchangeImpl) SubAccountTransfer(ctx context.Context, request SubAccountTransferRequest) (*TransferResponse, error) {
+	action := SubAccountTransferAction{
+		Type:           "subAccountTransfer",
+		SubAccountUser: strings.ToLower(request.SubAccount),
+		IsDeposit:      request.IsDeposit,
+		Usd:            int64(math.Round(request.Amount * 1e6)),
+	}
+
+	timestamp, err := e.nonceFor(e.agentOf(request.Address))
+	if err != nil {
+		return nil, err
+	}
+	res, err := e.postL1ActionWithNonce(ctx, request.Address, action, actionOptions{}, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.parseTransferResponse(ctx, res, timestamp)
+}
+
+// spotToken returns the spot token named "{name}:{tokenId}" like in spotSend actions
+func (e *ExchangeImpl) spotToken(ctx context.Context, name string) (SpotToken, error) {
+	spotMeta, err := e.infoApi.GetSpotMeta(ctx)
+	if err != nil {
+		return SpotToken{}, err
+	}
+	for _, token := range spotMeta.Tokens {
+		if strings.EqualFold(token.Name+":"+token.TokenId, name) {
+			return token, nil
+		}
+	}
+	return SpotToken{}, &UnknownAssetError{Coin: name}
+}
+
+// usdToWire formats a USDC amount, which has 6 decimals
+func usdToWire(x float64) string {
+	return amountToWire(x, 6)
+}
+
+func amountToWire(x float64, decimals int) string {
+	exp := math.Pow(10.0, float64(decimals))
+	return int64ToFixedSize(int64(math.Round(x*exp)), decimals)
+}
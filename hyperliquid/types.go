@@ -4,13 +4,24 @@ import (
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
 type AssetInfo struct {
+	// Name is the coin used by the API for this asset, e.g. "@107" for the HYPE/USDC spot pair
+	Name       string
 	SzDecimals int
 	AssetId    int
+	IsSpot     bool
+}
+
+func (i AssetInfo) PriceToWire(px float64) string {
+	if i.IsSpot {
+		return SpotPriceToWire(px, i.SzDecimals)
+	}
+	return PriceToWire(px, i.SzDecimals)
 }
 
 type OrderRequest struct {
@@ -60,9 +71,9 @@ const GroupingNa Grouping = "na"
 const GroupingTpSl Grouping = "positionTpsl"
 
 type RsvSignature struct {
-	R string `json:"r"`
-	S string `json:"s"`
-	V byte   `json:"v"`
+	R string `msgpack:"r" json:"r"`
+	S string `msgpack:"s" json:"s"`
+	V byte   `msgpack:"v" json:"v"`
 }
 
 type ExchangeRequest struct {
@@ -70,6 +81,7 @@ type ExchangeRequest struct {
 	Nonce        int64        `json:"nonce"`
 	Signature    RsvSignature `json:"signature"`
 	VaultAddress *string      `json:"vaultAddress"`
+	ExpiresAfter *int64       `json:"expiresAfter,omitempty"`
 }
 
 type Message struct {
@@ -78,9 +90,16 @@ type Message struct {
 }
 
 type PlaceOrderAction struct {
-	Type     string      `msgpack:"type" json:"type"`
-	Orders   []OrderWire `msgpack:"orders" json:"orders"`
-	Grouping Grouping    `msgpack:"grouping" json:"grouping"`
+	Type     string       `msgpack:"type" json:"type"`
+	Orders   []OrderWire  `msgpack:"orders" json:"orders"`
+	Grouping Grouping     `msgpack:"grouping" json:"grouping"`
+	Builder  *BuilderWire `msgpack:"builder,omitempty" json:"builder,omitempty"`
+}
+
+// BuilderWire is the builder code of an order, Fee is in tenths of a basis point
+type BuilderWire struct {
+	Address string `msgpack:"b" json:"b"`
+	Fee     int    `msgpack:"f" json:"f"`
 }
 
 type ModifyOrdersAction struct {
@@ -108,6 +127,43 @@ type CancelOidWire struct {
 	Oid   int64 `msgpack:"o" json:"o"`
 }
 
+type UpdateIsolatedMarginAction struct {
+	Type  string `msgpack:"type" json:"type"`
+	Asset int    `msgpack:"asset" json:"asset"`
+	IsBuy bool   `msgpack:"isBuy" json:"isBuy"`
+	Ntli  int64  `msgpack:"ntli" json:"ntli"`
+}
+
+type TwapOrderAction struct {
+	Type string   `msgpack:"type" json:"type"`
+	Twap TwapWire `msgpack:"twap" json:"twap"`
+}
+
+type TwapWire struct {
+	Asset      int    `msgpack:"a" json:"a"`
+	IsBuy      bool   `msgpack:"b" json:"b"`
+	Size       string `msgpack:"s" json:"s"`
+	ReduceOnly bool   `msgpack:"r" json:"r"`
+	Minutes    int    `msgpack:"m" json:"m"`
+	Randomize  bool   `msgpack:"t" json:"t"`
+}
+
+type TwapCancelAction struct {
+	Type   string `msgpack:"type" json:"type"`
+	Asset  int    `msgpack:"a" json:"a"`
+	TwapId int64  `msgpack:"t" json:"t"`
+}
+
+type ScheduleCancelAction struct {
+	Type string `msgpack:"type" json:"type"`
+	Time *int64 `msgpack:"time,omitempty" json:"time,omitempty"`
+}
+
+// NoopAction does nothing but use its nonce, which invalidates any other action signed with it
+type NoopAction struct {
+	Type string `msgpack:"type" json:"type"`
+}
+
 type UpdateLeverageAction struct {
 	Type     string `msgpack:"type" json:"type"`
 	Asset    int    `msgpack:"asset" json:"asset"`
@@ -117,13 +173,103 @@ type UpdateLeverageAction struct {
 
 type WithdrawAction struct {
 	Type             string `msgpack:"type" json:"type"`
+	SignatureChainId string `msgpack:"signatureChainId" json:"signatureChainId"`
 	HLChain          string `msgpack:"hyperliquidChain" json:"hyperliquidChain"`
+	Destination      string `msgpack:"destination" json:"destination"`
+	Amount           string `msgpack:"amount" json:"amount"`
+	Time             int64  `msgpack:"time" json:"time"`
+}
+
+type UsdSendAction struct {
+	Type             string `msgpack:"type" json:"type"`
 	SignatureChainId string `msgpack:"signatureChainId" json:"signatureChainId"`
+	HLChain          string `msgpack:"hyperliquidChain" json:"hyperliquidChain"`
 	Destination      string `msgpack:"destination" json:"destination"`
 	Amount           string `msgpack:"amount" json:"amount"`
 	Time             int64  `msgpack:"time" json:"time"`
 }
 
+type SpotSendAction struct {
+	Type             string `msgpack:"type" json:"type"`
+	SignatureChainId string `msgpack:"signatureChainId" json:"signatureChainId"`
+	HLChain          string `msgpack:"hyperliquidChain" json:"hyperliquidChain"`
+	Destination      string `msgpack:"destination" json:"destination"`
+	Token            string `msgpack:"token" json:"token"`
+	Amount           string `msgpack:"amount" json:"amount"`
+	Time             int64  `msgpack:"time" json:"time"`
+}
+
+type UsdClassTransferAction struct {
+	Type             string `msgpack:"type" json:"type"`
+	SignatureChainId string `msgpack:"signatureChainId" json:"signatureChainId"`
+	HLChain          string `msgpack:"hyperliquidChain" json:"hyperliquidChain"`
+	Amount           string `msgpack:"amount" json:"amount"`
+	ToPerp           bool   `msgpack:"toPerp" json:"toPerp"`
+	Nonce            int64  `msgpack:"nonce" json:"nonce"`
+}
+
+type ApproveAgentAction struct {
+	Type             string `msgpack:"type" json:"type"`
+	SignatureChainId string `msgpack:"signatureChainId" json:"signatureChainId"`
+	HLChain          string `msgpack:"hyperliquidChain" json:"hyperliquidChain"`
+	AgentAddress     string `msgpack:"agentAddress" json:"agentAddress"`
+	AgentName        string `msgpack:"agentName,omitempty" json:"agentName,omitempty"`
+	Nonce            int64  `msgpack:"nonce" json:"nonce"`
+}
+
+type ApproveBuilderFeeAction struct {
+	Type             string `msgpack:"type" json:"type"`
+	SignatureChainId string `msgpack:"signatureChainId" json:"signatureChainId"`
+	HLChain          string `msgpack:"hyperliquidChain" json:"hyperliquidChain"`
+	MaxFeeRate       string `msgpack:"maxFeeRate" json:"maxFeeRate"`
+	Builder          string `msgpack:"builder" json:"builder"`
+	Nonce            int64  `msgpack:"nonce" json:"nonce"`
+}
+
+type TokenDelegateAction struct {
+	Type             string `msgpack:"type" json:"type"`
+	SignatureChainId string `msgpack:"signatureChainId" json:"signatureChainId"`
+	HLChain          string `msgpack:"hyperliquidChain" json:"hyperliquidChain"`
+	Validator        string `msgpack:"validator" json:"validator"`
+	Wei              uint64 `msgpack:"wei" json:"wei"`
+	IsUndelegate     bool   `msgpack:"isUndelegate" json:"isUndelegate"`
+	Nonce            int64  `msgpack:"nonce" json:"nonce"`
+}
+
+// StakingTransferAction is used by cDeposit and cWithdraw to move HYPE between the spot and staking balances
+type StakingTransferAction struct {
+	Type             string `msgpack:"type" json:"type"`
+	SignatureChainId string `msgpack:"signatureChainId" json:"signatureChainId"`
+	HLChain          string `msgpack:"hyperliquidChain" json:"hyperliquidChain"`
+	Wei              uint64 `msgpack:"wei" json:"wei"`
+	Nonce            int64  `msgpack:"nonce" json:"nonce"`
+}
+
+type CreateSubAccountAction struct {
+	Type string `msgpack:"type" json:"type"`
+	Name string `msgpack:"name" json:"name"`
+}
+
+type SubAccountModifyAction struct {
+	Type           string `msgpack:"type" json:"type"`
+	SubAccountUser string `msgpack:"subAccountUser" json:"subAccountUser"`
+	Name           string `msgpack:"name" json:"name"`
+}
+
+type VaultTransferAction struct {
+	Type         string `msgpack:"type" json:"type"`
+	VaultAddress string `msgpack:"vaultAddress" json:"vaultAddress"`
+	IsDeposit    bool   `msgpack:"isDeposit" json:"isDeposit"`
+	Usd          int64  `msgpack:"usd" json:"usd"`
+}
+
+type SubAccountTransferAction struct {
+	Type           string `msgpack:"type" json:"type"`
+	SubAccountUser string `msgpack:"subAccountUser" json:"subAccountUser"`
+	IsDeposit      bool   `msgpack:"isDeposit" json:"isDeposit"`
+	Usd            int64  `msgpack:"usd" json:"usd"`
+}
+
 type OrderWire struct {
 	Asset      int           `msgpack:"a" json:"a"`
 	IsBuy      bool          `msgpack:"b" json:"b"`
@@ -153,7 +299,7 @@ type SigRequest struct {
 }
 
 func (req SigRequest) GetChainId() *math.HexOrDecimal256 {
-	if req.PrimaryType == "HyperliquidTransaction:Withdraw" || req.PrimaryType == "Hyperliquid:UserPoints" {
+	if isUserSignedType(req.PrimaryType) {
 		if req.IsMainNet {
 			return math.NewHexOrDecimal256(int64(42161))
 		} else {
@@ -199,6 +345,35 @@ type WithdrawRequest struct {
 	Amount      float64
 }
 
+type UsdSendRequest struct {
+	Address     string
+	Destination string
+	Amount      float64
+}
+
+// SpotSendRequest sends Amount of Token, which is identified as "NAME:tokenId", e.g. "PURR:0xc4bf3f870c0e9465323c0b6ed28096c2"
+type SpotSendRequest struct {
+	Address     string
+	Destination string
+	Token       string
+	Amount      float64
+}
+
+// UsdClassTransferRequest moves USDC from the spot to the perp balance when ToPerp is true, or the other way around
+type UsdClassTransferRequest struct {
+	Address string
+	Amount  float64
+	ToPerp  bool
+}
+
+// SubAccountTransferRequest moves USDC from Address to SubAccount when IsDeposit is true, or the other way around
+type SubAccountTransferRequest struct {
+	Address    string
+	SubAccount string
+	IsDeposit  bool
+	Amount     float64
+}
+
 type WithdrawWire struct {
 	Destination string `json:"destination"`
 	Amount      string `json:"amount"`
@@ -206,6 +381,19 @@ type WithdrawWire struct {
 	Time        int64  `json:"time"`
 }
 
+// CancelRequest identifies an order to cancel by either Oid or Cloid
+type CancelRequest struct {
+	Coin  string
+	Oid   *int64
+	Cloid *string
+}
+
+// CancelResult is the outcome of a single CancelRequest, Err is nil when the order was cancelled
+type CancelResult struct {
+	Request CancelRequest
+	Err     error
+}
+
 type UpdateLeverageRequest struct {
 	Address  string
 	Coin     string
@@ -283,6 +471,47 @@ func unmarshalCancelOrderResponse(data []byte) (response *CancelOrderResponse, e
 	return response, nil
 }
 
+func unmarshalTwapOrderResponse(data []byte) (response *TwapOrderResponse, err error) {
+	response = &TwapOrderResponse{
+		Response: new(InnerTwapOrderResponse),
+	}
+	response.Status, response.ResponseErr, err = unmarshalInnerResponse(data, response.Response)
+	if err != nil {
+		return nil, err
+	}
+	if response.ResponseErr != nil {
+		response.Response = nil
+	}
+	return response, nil
+}
+
+func unmarshalTwapCancelResponse(data []byte) (response *TwapCancelResponse, err error) {
+	response = &TwapCancelResponse{
+		Response: new(InnerTwapCancelResponse),
+	}
+	response.Status, response.ResponseErr, err = unmarshalInnerResponse(data, response.Response)
+	if err != nil {
+		return nil, err
+	}
+	if response.ResponseErr != nil {
+		response.Response = nil
+	}
+	return response, nil
+}
+
+func unmarshalActionResponse(data []byte) (response *ActionResponse, err error) {
+	var inner struct {
+		Type string `json:"type"`
+	}
+	response = &ActionResponse{}
+	response.Status, response.ResponseErr, err = unmarshalInnerResponse(data, &inner)
+	if err != nil {
+		return nil, err
+	}
+	response.Type = inner.Type
+	return response, nil
+}
+
 type PlaceOrderResponse struct {
 	Status      string         `json:"status"`
 	ResponseErr *string        // json["response"] is either ResponseErr or Response
@@ -295,11 +524,81 @@ type ModifyOrderResponse struct {
 	Response    *InnerResponse // json["response"] is either ResponseErr or Response
 }
 
-type WithdrawResponse struct {
+// ActionResponse is the response of actions which don't return order statuses, like updateLeverage
+type ActionResponse struct {
+	Status      string  `json:"status"`
+	ResponseErr *string // json["response"] is either ResponseErr or an object with the response type
+	Type        string  `json:"type"`
+}
+
+// TransferResponse is the result of an action moving funds, Nonce identifies it in the ledger updates
+type TransferResponse struct {
 	Status string `json:"status"`
+	Type   string `json:"type"`
 	Nonce  int64
 }
 
+type WithdrawResponse = TransferResponse
+
+// ApproveAgentResponse holds the generated agent key, it must be stored by the caller as it can't be recovered
+type ApproveAgentResponse struct {
+	TransferResponse
+	AgentAddress string
+	AgentKey     *ecdsa.PrivateKey
+}
+
+type TwapOrderResponse struct {
+	Status      string                  `json:"status"`
+	ResponseErr *string                 // json["response"] is either ResponseErr or Response
+	Response    *InnerTwapOrderResponse // json["response"] is either ResponseErr or Response
+}
+
+type InnerTwapOrderResponse struct {
+	Type string `json:"type"`
+	Data struct {
+		Status TwapStatus `json:"status"`
+	} `json:"data"`
+}
+
+// TwapStatus is either the id of the started TWAP or the reason it was rejected
+type TwapStatus struct {
+	Running *struct {
+		TwapId int64 `json:"twapId"`
+	} `json:"running"`
+	Error *string `json:"error"`
+}
+
+// TwapId returns the id of the started TWAP, false when it was rejected
+func (r TwapOrderResponse) TwapId() (int64, bool) {
+	if r.Response == nil || r.Response.Data.Status.Running == nil {
+		return 0, false
+	}
+	return r.Response.Data.Status.Running.TwapId, true
+}
+
+type TwapCancelResponse struct {
+	Status      string                   `json:"status"`
+	ResponseErr *string                  // json["response"] is either ResponseErr or Response
+	Response    *InnerTwapCancelResponse // json["response"] is either ResponseErr or Response
+}
+
+type InnerTwapCancelResponse struct {
+	Type string `json:"type"`
+	Data struct {
+		Status CancelStatus `json:"status"`
+	} `json:"data"`
+}
+
+func (r TwapCancelResponse) IsCancelled() bool {
+	return r.Status == "ok" && r.Response != nil && r.Response.Data.Status.Success
+}
+
+// TwapSliceFill is a fill of one of the orders a TWAP was split into
+type TwapSliceFill struct {
+	Fill   OrderFill `json:"fill"`
+	TwapId int64     `json:"twapId"`
+}
+
 type CancelOrderResponse struct {
 	Status      string               `json:"status"`
 	ResponseErr *string              // json["response"] is either ResponseErr or Response
@@ -357,7 +656,32 @@ type DataResponse struct {
 }
 
 type CancelDataResponse struct {
-	Statuses []string `json:"statuses"`
+	Statuses []CancelStatus `json:"statuses"`
+}
+
+// CancelStatus is either "success" or an object with the reason the order couldn't be cancelled
+type CancelStatus struct {
+	Success bool
+	Error   *string
+}
+
+func (s *CancelStatus) UnmarshalJSON(data []byte) error {
+	var status string
+	if err := json.Unmarshal(data, &status); err == nil {
+		s.Success = status == "success"
+		if !s.Success {
+			s.Error = &status
+		}
+		return nil
+	}
+	var statusErr struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &statusErr); err != nil {
+		return err
+	}
+	s.Error = &statusErr.Error
+	return nil
 }
 
 func (r CancelOrderResponse) IsCancelled() bool {
@@ -365,10 +689,7 @@ func (r CancelOrderResponse) IsCancelled() bool {
 		return false
 	}
 	for _, status := range r.Response.Data.Statuses {
-		if status == "success" {
-			return true
-		}
-		return false
+		return status.Success
 	}
 	return false
 }
@@ -400,30 +721,37 @@ type OpenOrder struct {
 	Timestamp int64  `json:"timestamp"`
 }
 
+// FrontendOrder is the full shape of an order, Children holds the TP/SL orders attached to it
+type FrontendOrder struct {
+	Children         []FrontendOrder `json:"children"`
+	Cloid            string          `json:"cloid"`
+	Coin             string          `json:"coin"`
+	IsPositionTpsl   bool            `json:"isPositionTpsl"`
+	IsTrigger        bool            `json:"isTrigger"`
+	LimitPx          string          `json:"limitPx"`
+	Oid              int64           `json:"oid"`
+	OrderType        string          `json:"orderType"`
+	OrigSz           string          `json:"origSz"`
+	ReduceOnly       bool            `json:"reduceOnly"`
+	Side             string          `json:"side"`
+	Sz               string          `json:"sz"`
+	Tif              string          `json:"tif"`
+	Timestamp        int64           `json:"timestamp"`
+	TriggerCondition string          `json:"triggerCondition"`
+	TriggerPx        string          `json:"triggerPx"`
+}
+
+// OrderWithStatus is an order and its latest status, e.g. "open", "filled", "canceled", "triggered",
+// "rejected" or "marginCanceled"
+type OrderWithStatus struct {
+	Order           FrontendOrder `json:"order"`
+	Status          string        `json:"status"`
+	StatusTimestamp int64         `json:"statusTimestamp"`
+}
+
 type OrderResponse struct {
-	Order struct {
-		Order struct {
-			Children         []any  `json:"children"`
-			Cloid            string `json:"cloid"`
-			Coin             string `json:"coin"`
-			IsPositionTpsl   bool   `json:"isPositionTpsl"`
-			IsTrigger        bool   `json:"isTrigger"`
-			LimitPx          string `json:"limitPx"`
-			Oid              int64  `json:"oid"`
-			OrderType        string `json:"orderType"`
-			OrigSz           string `json:"origSz"`
-			ReduceOnly       bool   `json:"reduceOnly"`
-			Side             string `json:"side"`
-			Sz               string `json:"sz"`
-			Tif              string `json:"tif"`
-			Timestamp        int64  `json:"timestamp"`
-			TriggerCondition string `json:"triggerCondition"`
-			TriggerPx        string `json:"triggerPx"`
-		} `json:"order"`
-		Status          string `json:"status"`
-		StatusTimestamp int64  `json:"statusTimestamp"`
-	} `json:"order"`
-	Status string `json:"status"`
+	Order  OrderWithStatus `json:"order"`
+	Status string          `json:"status"`
 }
 
 type OrderFill struct {
@@ -0,0 +1,333 @@
+package hyperliquid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// userSignedType is the EIP-712 type of an action signed by the user instead of an agent. The message
+// is built from the action fields with the same json name, so adding an action only requires its struct
+// and an entry in userSignedTypes.
+type userSignedType struct {
+	PrimaryType string
+	Fields      []apitypes.Type
+}
+
+var hyperliquidChainField = apitypes.Type{Name: "hyperliquidChain", Type: "string"}
+
+// userSignedTypes maps the type of user signed actions to their EIP-712 type
+var userSignedTypes = map[string]userSignedType{
+	"withdraw3": {
+		PrimaryType: "HyperliquidTransaction:Withdraw",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "destination", Type: "string"},
+			{Name: "amount", Type: "string"},
+			{Name: "time", Type: "uint64"},
+		},
+	},
+	"usdSend": {
+		PrimaryType: "HyperliquidTransaction:UsdSend",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "destination", Type: "string"},
+			{Name: "amount", Type: "string"},
+			{Name: "time", Type: "uint64"},
+		},
+	},
+	"spotSend": {
+		PrimaryType: "HyperliquidTransaction:SpotSend",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "destination", Type: "string"},
+			{Name: "token", Type: "string"},
+			{Name: "amount", Type: "string"},
+			{Name: "time", Type: "uint64"},
+		},
+	},
+	"usdClassTransfer": {
+		PrimaryType: "HyperliquidTransaction:UsdClassTransfer",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "amount", Type: "string"},
+			{Name: "toPerp", Type: "bool"},
+			{Name: "nonce", Type: "uint64"},
+		},
+	},
+	"approveAgent": {
+		PrimaryType: "HyperliquidTransaction:ApproveAgent",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "agentAddress", Type: "address"},
+			{Name: "agentName", Type: "string"},
+			{Name: "nonce", Type: "uint64"},
+		},
+	},
+	"approveBuilderFee": {
+		PrimaryType: "HyperliquidTransaction:ApproveBuilderFee",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "maxFeeRate", Type: "string"},
+			{Name: "builder", Type: "address"},
+			{Name: "nonce", Type: "uint64"},
+		},
+	},
+	"tokenDelegate": {
+		PrimaryType: "HyperliquidTransaction:TokenDelegate",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "validator", Type: "address"},
+			{Name: "wei", Type: "uint64"},
+			{Name: "isUndelegate", Type: "bool"},
+			{Name: "nonce", Type: "uint64"},
+		},
+	},
+	"cDeposit": {
+		PrimaryType: "HyperliquidTransaction:CDeposit",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "wei", Type: "uint64"},
+			{Name: "nonce", Type: "uint64"},
+		},
+	},
+	"cWithdraw": {
+		PrimaryType: "HyperliquidTransaction:CWithdraw",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "wei", Type: "uint64"},
+			{Name: "nonce", Type: "uint64"},
+		},
+	},
+	"convertToMultiSigUser": {
+		PrimaryType: "HyperliquidTransaction:ConvertToMultiSigUser",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "signers", Type: "string"},
+			{Name: "nonce", Type: "uint64"},
+		},
+	},
+	"sendMultiSig": {
+		PrimaryType: "HyperliquidTransaction:SendMultiSig",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "multiSigActionHash", Type: "bytes32"},
+			{Name: "nonce", Type: "uint64"},
+		},
+	},
+	"userPoints2": {
+		PrimaryType: "Hyperliquid:UserPoints",
+		Fields: []apitypes.Type{
+			hyperliquidChainField,
+			{Name: "time", Type: "uint64"},
+		},
+	},
+}
+
+// isUserSignedType is true for the primary types of userSignedTypes, which use the HyperliquidSignTransaction domain
+func isUserSignedType(primaryType string) bool {
+	for _, t := range userSignedTypes {
+		if t.PrimaryType == primaryType {
+			return true
+		}
+	}
+	return false
+}
+
+// userSignedAction is implemented by user signed actions, so their hyperliquidChain and signatureChainId are
+// set from the network they are sent to instead of by each caller
+type userSignedAction interface {
+	withChain(hyperliquidChain string, signatureChainId string) any
+}
+
+func (a WithdrawAction) withChain(chain string, chainId string) any {
+	a.HLChain, a.SignatureChainId = chain, chainId
+	return a
+}
+
+func (a UsdSendAction) withChain(chain string, chainId string) any {
+	a.HLChain, a.SignatureChainId = chain, chainId
+	return a
+}
+
+func (a SpotSendAction) withChain(chain string, chainId string) any {
+	a.HLChain, a.SignatureChainId = chain, chainId
+	return a
+}
+
+func (a UsdClassTransferAction) withChain(chain string, chainId string) any {
+	a.HLChain, a.SignatureChainId = chain, chainId
+	return a
+}
+
+func (a ApproveAgentAction) withChain(chain string, chainId string) any {
+	a.HLChain, a.SignatureChainId = chain, chainId
+	return a
+}
+
+func (a ApproveBuilderFeeAction) withChain(chain string, chainId string) any {
+	a.HLChain, a.SignatureChainId = chain, chainId
+	return a
+}
+
+func (a TokenDelegateAction) withChain(chain string, chainId string) any {
+	a.HLChain, a.SignatureChainId = chain, chainId
+	return a
+}
+
+func (a StakingTransferAction) withChain(chain string, chainId string) any {
+	a.HLChain, a.SignatureChainId = chain, chainId
+	return a
+}
+
+func (a ConvertToMultiSigUserAction) withChain(chain string, chainId string) any {
+	a.HLChain, a.SignatureChainId = chain, chainId
+	return a
+}
+
+// the envelope signed by the outer signer of a multi-sig action isn't sent, so it has no signatureChainId
+func (a sendMultiSigEnvelope) withChain(chain string, _ string) any {
+	a.HLChain = chain
+	return a
+}
+
+// withChain sets the chain fields of a user signed action for the network of the API, other actions are unchanged
+func (e *ExchangeImpl) withChain(action any) any {
+	if a, ok := action.(userSignedAction); ok {
+		return a.withChain(e.chain())
+	}
+	return action
+}
+
+// userSignedMessage returns the EIP-712 type and message of a user signed action, string fields omitted
+// from the action are signed as empty strings. The chain fields of the action must be the ones of the network,
+// the exchange rejects signatures made for another one.
+func userSignedMessage(action any, mainnet bool) (userSignedType, apitypes.TypedDataMessage, error) {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return userSignedType{}, nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return userSignedType{}, nil, err
+	}
+
+	actionType, _ := fields["type"].(string)
+	t, ok := userSignedTypes[actionType]
+	if !ok {
+		return userSignedType{}, nil, fmt.Errorf("%q is not a user signed action", actionType)
+	}
+
+	chain, chainId := UserSignedChain(mainnet)
+	if fields["hyperliquidChain"] != chain {
+		return userSignedType{}, nil, fmt.Errorf("action %s has hyperliquidChain %v instead of %s", actionType, fields["hyperliquidChain"], chain)
+	}
+	if id, ok := fields["signatureChainId"]; ok && id != chainId {
+		return userSignedType{}, nil, fmt.Errorf("action %s has signatureChainId %v instead of %s", actionType, id, chainId)
+	}
+
+	message := apitypes.TypedDataMessage{}
+	for _, field := range t.Fields {
+		value, ok := fields[field.Name]
+		if !ok && field.Type == "string" {
+			value, ok = "", true
+		}
+		if !ok {
+			return userSignedType{}, nil, fmt.Errorf("action %s has no field %s", actionType, field.Name)
+		}
+		if number, ok := value.(json.Number); ok {
+			value = number.String()
+		}
+		message[field.Name] = value
+	}
+
+	return t, message, nil
+}
+
+// UserSignedChain returns the hyperliquidChain and signatureChainId of user signed actions
+func UserSignedChain(isMainnet bool) (string, string) {
+	if isMainnet {
+		return "Mainnet", "0xa4b1"
+	}
+	return "Testnet", "0x66eee"
+}
+
+func (e *ExchangeImpl) chain() (string, string) {
+	return UserSignedChain((*e.cli).IsMainnet())
+}
+
+// SignUserSignedAction signs an action of userSignedTypes with the key of address, the action hyperliquidChain
+// and signatureChainId must be the ones returned by UserSignedChain for the network of the API
+func (e *ExchangeImpl) SignUserSignedAction(ctx context.Context, address string, action any) (byte, [32]byte, [32]byte, error) {
+	return e.signUserSignedAction(ctx, address, action, (*e.cli).IsMainnet())
+}
+
+func (e *ExchangeImpl) signUserSignedAction(ctx context.Context, address string, action any, mainnet bool) (byte, [32]byte, [32]byte, error) {
+	t, message, err := userSignedMessage(action, mainnet)
+	if err != nil {
+		return 0, [32]byte{}, [32]byte{}, &SigningError{Address: address, Err: err}
+	}
+
+	signer := NewSigner(e.keyManager)
+	req := SigRequest{
+		PrimaryType: t.PrimaryType,
+		DType:       t.Fields,
+		DTypeMsg:    message,
+		IsMainNet:   mainnet,
+	}
+
+	v, r, s, err := signer.Sign(address, req)
+	if err != nil {
+		e.logger.LogErr(ctx, "Failed to sign request", err)
+		return 0, [32]byte{}, [32]byte{}, &SigningError{Address: address, Err: err}
+	}
+
+	return v, r, s, nil
+}
+
+// postUserSignedAction sets the chain of a user signed action, then signs and posts it. nonce must be the one
+// used in the action.
+func (e *ExchangeImpl) postUserSignedAction(ctx context.Context, address string, action any, nonce int64) (*TransferResponse, error) {
+	action = e.withChain(action)
+	v, r, s, err := e.SignUserSignedAction(ctx, address, action)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.postSignedAction(ctx, action, nonce, ToTypedSig(r, s, v))
+}
+
+// postSignedAction posts an action which is already signed
+func (e *ExchangeImpl) postSignedAction(ctx context.Context, action any, nonce int64, signature RsvSignature) (*TransferResponse, error) {
+	payload := ExchangeRequest{
+		Action:    action,
+		Nonce:     nonce,
+		Signature: signature,
+	}
+
+	res, err := (*e.cli).Post(ctx, "/exchange", payload)
+	if err != nil {
+		return nil, err
+	}
+	e.logger.LogInfo(ctx, fmt.Sprintf("Response is %s", res))
+
+	return e.parseTransferResponse(ctx, res, nonce)
+}
+
+func (e *ExchangeImpl) parseTransferResponse(ctx context.Context, res json.RawMessage, nonce int64) (*TransferResponse, error) {
+	response, err := e.parseActionResponse(ctx, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransferResponse{
+		Status: response.Status,
+		Type:   response.Type,
+		Nonce:  nonce,
+	}, nil
+}
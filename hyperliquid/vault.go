@@ -0,0 +1,132 @@
+package hyperliquid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+type VaultDetails struct {
+	Name                  string            `json:"name"`
+	VaultAddress          string            `json:"vaultAddress"`
+	Leader                string            `json:"leader"`
+	Description           string            `json:"description"`
+	Portfolio             Portfolio         `json:"portfolio"`
+	Apr                   float64           `json:"apr"`
+	FollowerState         *VaultFollower    `json:"followerState"`
+	LeaderFraction        float64           `json:"leaderFraction"`
+	LeaderCommission      float64           `json:"leaderCommission"`
+	Followers             []VaultFollower   `json:"followers"`
+	MaxDistributable      float64           `json:"maxDistributable"`
+	MaxWithdrawable       float64           `json:"maxWithdrawable"`
+	IsClosed              bool              `json:"isClosed"`
+	Relationship          VaultRelationship `json:"relationship"`
+	AllowDeposits         bool              `json:"allowDeposits"`
+	AlwaysCloseOnWithdraw bool              `json:"alwaysCloseOnWithdraw"`
+}
+
+// VaultFollower is a depositor of a vault, it can't withdraw before LockupUntil
+type VaultFollower struct {
+	User           string `json:"user"`
+	VaultEquity    string `json:"vaultEquity"`
+	Pnl            string `json:"pnl"`
+	AllTimePnl     string `json:"allTimePnl"`
+	DaysFollowing  int    `json:"daysFollowing"`
+	VaultEntryTime int64  `json:"vaultEntryTime"`
+	LockupUntil    int64  `json:"lockupUntil"`
+}
+
+// VaultRelationship is "normal", "child" or "parent", in which case Data holds the child vaults
+type VaultRelationship struct {
+	Type string `json:"type"`
+	Data *struct {
+		ChildAddresses []string `json:"childAddresses"`
+	} `json:"data"`
+}
+
+// VaultEquity is the equity of a user in a vault, it can't be withdrawn before LockedUntilTimestamp
+type VaultEquity struct {
+	VaultAddress         string `json:"vaultAddress"`
+	Equity               string `json:"equity"`
+	LockedUntilTimestamp int64  `json:"lockedUntilTimestamp"`
+}
+
+// Portfolio maps periods like "day", "week", "month" and "allTime" to the performance in that period
+type Portfolio map[string]PortfolioPeriod
+
+type PortfolioPeriod struct {
+	AccountValueHistory []HistoryPoint `json:"accountValueHistory"`
+	PnlHistory          []HistoryPoint `json:"pnlHistory"`
+	Vlm                 string         `json:"vlm"`
+}
+
+type HistoryPoint struct {
+	Time  int64
+	Value string
+}
+
+// UnmarshalJSON reads the [[period, performance], ...] array returned by the API
+func (p *Portfolio) UnmarshalJSON(data []byte) error {
+	periods, err := unmarshalPeriods[PortfolioPeriod](data)
+	*p = periods
+	return err
+}
+
+// unmarshalPeriods reads the [[period, value], ...] arrays used by the API for values over several periods
+func unmarshalPeriods[T any](data []byte) (map[string]T, error) {
+	var raw [][2]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	periods := make(map[string]T, len(raw))
+	for _, entry := range raw {
+		var period string
+		if err := json.Unmarshal(entry[0], &period); err != nil {
+			return nil, err
+		}
+		var value T
+		if err := json.Unmarshal(entry[1], &value); err != nil {
+			return nil, err
+		}
+		periods[period] = value
+	}
+	return periods, nil
+}
+
+// UnmarshalJSON reads the [time, value] array returned by the API
+func (h *HistoryPoint) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 2 {
+		return fmt.Errorf("expected time and value, got %d elements", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &h.Time); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[1], &h.Value)
+}
+
+// VaultTransfer deposits usd to a vault or withdraws it when isDeposit is false
+func (e *ExchangeImpl) VaultTransfer(ctx context.Context, address string, vault string, isDeposit bool, usd float64) (*TransferResponse, error) {
+	action := VaultTransferAction{
+		Type:         "vaultTransfer",
+		VaultAddress: strings.ToLower(vault),
+		IsDeposit:    isDeposit,
+		Usd:          int64(math.Round(usd * 1e6)),
+	}
+
+	timestamp, err := e.nonceFor(e.agentOf(address))
+	if err != nil {
+		return nil, err
+	}
+	res, err := e.postL1ActionWithNonce(ctx, address, action, actionOptions{}, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.parseTransferResponse(ctx, res, timestamp)
+}
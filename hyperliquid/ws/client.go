@@ -0,0 +1,317 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mfgmateus/hyperliquid-go-sdk/v3/hyperliquid"
+)
+
+const MainnetUrl = "wss://api.hyperliquid.xyz/ws"
+const TestnetUrl = "wss://api.hyperliquid-testnet.xyz/ws"
+
+// The server closes connections which have not sent a message in the last 60 seconds
+const DefaultPingInterval = 50 * time.Second
+const DefaultReconnectDelay = time.Second
+const DefaultBufferSize = 100
+
+var ErrClosed = errors.New("websocket client is closed")
+
+// ErrOtherUserSubscribed is returned when subscribing to orderUpdates or userEvents for a user while another user
+// is subscribed to it on the same client, their messages can't be told apart so each user needs its own client
+var ErrOtherUserSubscribed = errors.New("channel is already subscribed for another user on this connection")
+
+// Client keeps a websocket connection to the API, reconnecting and resubscribing when it drops.
+// PingInterval, ReconnectDelay and BufferSize can be changed before calling Connect.
+type Client struct {
+	PingInterval   time.Duration
+	ReconnectDelay time.Duration
+	BufferSize     int
+
+	url    string
+	logger hyperliquid.Logger
+	dialer *websocket.Dialer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	conn       *websocket.Conn
+	subs       map[string][]*subscriber
+	pending    map[int64]chan postResult
+	nextPostId int64
+
+	writeMu sync.Mutex
+}
+
+func NewClient(url string, logger hyperliquid.Logger) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		PingInterval:   DefaultPingInterval,
+		ReconnectDelay: DefaultReconnectDelay,
+		BufferSize:     DefaultBufferSize,
+		url:            url,
+		logger:         logger,
+		dialer:         websocket.DefaultDialer,
+		ctx:            ctx,
+		cancel:         cancel,
+		subs:           make(map[string][]*subscriber),
+		pending:        make(map[int64]chan postResult),
+	}
+}
+
+// Connect dials the server and starts reading messages in the background.
+// Subscriptions made before Connect are sent as soon as the connection is up.
+func (c *Client) Connect(ctx context.Context) error {
+	if c.ctx.Err() != nil {
+		return ErrClosed
+	}
+	conn, _, err := c.dialer.DialContext(ctx, c.url, nil)
+	if err != nil {
+		return &hyperliquid.TransportError{Path: c.url, Err: err}
+	}
+	c.setConn(conn)
+	go c.run(conn)
+	return nil
+}
+
+// Close stops the client, closing the connection and every subscription channel
+func (c *Client) Close() error {
+	c.cancel()
+
+	c.mu.Lock()
+	conn := c.conn
+	c.conn = nil
+	subs := c.subs
+	c.subs = make(map[string][]*subscriber)
+	c.mu.Unlock()
+
+	for _, list := range subs {
+		for _, sub := range list {
+			sub.close()
+		}
+	}
+
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+func (c *Client) setConn(conn *websocket.Conn) {
+	c.mu.Lock()
+	c.conn = conn
+	var requests []SubscriptionRequest
+	for _, list := range c.subs {
+		if len(list) > 0 {
+			requests = append(requests, list[0].request)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, req := range requests {
+		req := req
+		if err := c.write(wsRequest{Method: "subscribe", Subscription: &req}); err != nil {
+			c.logger.LogErr(c.ctx, fmt.Sprintf("Failed to subscribe to %s", req.identifier()), err)
+		}
+	}
+}
+
+func (c *Client) run(conn *websocket.Conn) {
+	for {
+		stop := make(chan struct{})
+		go c.ping(conn, stop)
+		err := c.read(conn)
+		close(stop)
+		_ = conn.Close()
+
+		c.mu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.mu.Unlock()
+		c.failPending(err)
+
+		if c.ctx.Err() != nil {
+			return
+		}
+		c.logger.LogErr(c.ctx, "Websocket connection lost, reconnecting", err)
+
+		conn = c.reconnect()
+		if conn == nil {
+			return
+		}
+	}
+}
+
+func (c *Client) reconnect() *websocket.Conn {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(c.ReconnectDelay):
+		}
+
+		conn, _, err := c.dialer.DialContext(c.ctx, c.url, nil)
+		if err != nil {
+			c.logger.LogErr(c.ctx, "Failed to reconnect websocket", err)
+			continue
+		}
+		c.setConn(conn)
+		return conn
+	}
+}
+
+func (c *Client) ping(conn *websocket.Conn, stop chan struct{}) {
+	ticker := time.NewTicker(c.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.writeTo(conn, wsRequest{Method: "ping"}); err != nil {
+				c.logger.LogErr(c.ctx, "Failed to send ping", err)
+				return
+			}
+		}
+	}
+}
+
+func (c *Client) read(conn *websocket.Conn) error {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		c.handleMessage(data)
+	}
+}
+
+func (c *Client) handleMessage(data []byte) {
+	var msg wsMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.logger.LogErr(c.ctx, fmt.Sprintf("Failed to parse websocket message: %s", data), err)
+		return
+	}
+
+	switch msg.Channel {
+	case "pong", "subscriptionResponse":
+		return
+	case "post":
+		c.handlePost(msg.Data)
+		return
+	case "error":
+		c.logger.LogErr(c.ctx, "Websocket error", fmt.Errorf("%s", msg.Data))
+		return
+	}
+
+	identifier, err := messageIdentifier(msg)
+	if err != nil {
+		c.logger.LogErr(c.ctx, fmt.Sprintf("Failed to parse %s message: %s", msg.Channel, msg.Data), err)
+		return
+	}
+
+	c.mu.Lock()
+	subs := append([]*subscriber(nil), c.subs[identifier]...)
+	c.mu.Unlock()
+
+	for _, sub := range subs {
+		if !sub.deliver(msg.Data) && sub.dropped.CompareAndSwap(false, true) {
+			c.logger.LogErr(c.ctx, fmt.Sprintf("Dropping %s subscription", identifier), errors.New("subscription buffer is full"))
+			// unsubscribing writes to the connection, which must not block the reader
+			go func(sub *subscriber) {
+				if err := c.removeSubscriber(sub); err != nil {
+					c.logger.LogErr(c.ctx, fmt.Sprintf("Failed to unsubscribe from %s", identifier), err)
+				}
+			}(sub)
+		}
+	}
+}
+
+func (c *Client) write(msg any) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return ErrClosed
+	}
+	return c.writeTo(conn, msg)
+}
+
+func (c *Client) writeTo(conn *websocket.Conn, msg any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return conn.WriteJSON(msg)
+}
+
+func (c *Client) addSubscriber(sub *subscriber) error {
+	identifier := sub.request.identifier()
+
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	first := len(c.subs[identifier]) == 0
+	if !first && sub.request.userless() && !strings.EqualFold(c.subs[identifier][0].request.User, sub.request.User) {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: %s for %s", ErrOtherUserSubscribed, sub.request.Type, c.subs[identifier][0].request.User)
+	}
+	c.subs[identifier] = append(c.subs[identifier], sub)
+	connected := c.conn != nil
+	c.mu.Unlock()
+
+	if !first || !connected {
+		return nil
+	}
+	// when the write fails the connection is broken, and the subscription is sent again after reconnecting
+	if err := c.write(wsRequest{Method: "subscribe", Subscription: &sub.request}); err != nil {
+		c.logger.LogErr(c.ctx, fmt.Sprintf("Failed to subscribe to %s", identifier), err)
+	}
+	return nil
+}
+
+func (c *Client) removeSubscriber(sub *subscriber) error {
+	identifier := sub.request.identifier()
+
+	c.mu.Lock()
+	list := c.subs[identifier]
+	found := false
+	for i, s := range list {
+		if s == sub {
+			list = append(list[:i], list[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(c.subs, identifier)
+	} else {
+		c.subs[identifier] = list
+	}
+	connected := c.conn != nil
+	c.mu.Unlock()
+
+	sub.close()
+
+	if !found || len(list) > 0 || !connected {
+		return nil
+	}
+	return c.write(wsRequest{Method: "unsubscribe", Subscription: &sub.request})
+}
+
+type subscriber struct {
+	request SubscriptionRequest
+	// deliver sends a message to the subscription without blocking, it is false when the buffer is full
+	deliver func(data json.RawMessage) bool
+	close   func()
+	dropped atomic.Bool
+}
@@ -0,0 +1,262 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mfgmateus/hyperliquid-go-sdk/v3/hyperliquid"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer is a local stand-in for the websocket endpoint, recording the requests it receives
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	conns    []*websocket.Conn
+	requests chan wsRequest
+}
+
+func newTestServer(t *testing.T) *testServer {
+	return newServer(t, true)
+}
+
+// newServer starts the stand-in, when read is false reading from the connections is left to the test
+func newServer(t *testing.T, read bool) *testServer {
+	s := &testServer{requests: make(chan wsRequest, 100)}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+
+		for read {
+			var req wsRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			s.requests <- req
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) url() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+func (s *testServer) lastConn() *websocket.Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns[len(s.conns)-1]
+}
+
+func (s *testServer) send(t *testing.T, channel string, data any) {
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+	require.NoError(t, s.lastConn().WriteJSON(wsMessage{Channel: channel, Data: raw}))
+}
+
+func (s *testServer) expect(t *testing.T, method string) wsRequest {
+	for {
+		select {
+		case req := <-s.requests:
+			if req.Method == method {
+				return req
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no %s request received", method)
+		}
+	}
+}
+
+func newTestClient(t *testing.T, s *testServer) *Client {
+	client := NewClient(s.url(), &hyperliquid.DefaultLogger{})
+	client.ReconnectDelay = 10 * time.Millisecond
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func receive[T any](t *testing.T, c <-chan T) T {
+	select {
+	case v, ok := <-c:
+		require.True(t, ok, "subscription channel closed")
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message received")
+	}
+	var zero T
+	return zero
+}
+
+func TestSubscribeAllMids(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	require.NoError(t, client.Connect(context.Background()))
+
+	sub, err := client.SubscribeAllMids()
+	require.NoError(t, err)
+	req := server.expect(t, "subscribe")
+	require.Equal(t, "allMids", req.Subscription.Type)
+
+	server.send(t, "subscriptionResponse", req)
+	server.send(t, "allMids", AllMids{Mids: map[string]string{"ETH": "3000.5"}})
+
+	mids := receive(t, sub.C)
+	require.Equal(t, "3000.5", mids.Mids["ETH"])
+
+	require.NoError(t, sub.Unsubscribe())
+	req = server.expect(t, "unsubscribe")
+	require.Equal(t, "allMids", req.Subscription.Type)
+	_, ok := <-sub.C
+	require.False(t, ok)
+}
+
+func TestSubscriptionRouting(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	// subscriptions made before connecting are sent once connected
+	eth, err := client.SubscribeL2Book("ETH", nil, nil)
+	require.NoError(t, err)
+	btc, err := client.SubscribeL2Book("BTC", nil, nil)
+	require.NoError(t, err)
+	fills, err := client.SubscribeUserFills("0xABC", false)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Connect(context.Background()))
+	for i := 0; i < 3; i++ {
+		server.expect(t, "subscribe")
+	}
+
+	server.send(t, "l2Book", L2Book{Coin: "BTC", Levels: [2][]L2Level{{{Px: "60000", Sz: "1", N: 2}}, {}}})
+	server.send(t, "l2Book", L2Book{Coin: "ETH", Levels: [2][]L2Level{{{Px: "3000", Sz: "2", N: 1}}, {}}})
+	server.send(t, "userFills", UserFills{User: "0xabc", Fills: []hyperliquid.OrderFill{{Coin: "ETH", Tid: 7}}})
+
+	require.Equal(t, "60000", receive(t, btc.C).Levels[0][0].Px)
+	require.Equal(t, "3000", receive(t, eth.C).Levels[0][0].Px)
+	require.Equal(t, int64(7), receive(t, fills.C).Fills[0].Tid)
+}
+
+func TestReconnectResubscribes(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	require.NoError(t, client.Connect(context.Background()))
+
+	sub, err := client.SubscribeTrades("ETH")
+	require.NoError(t, err)
+	server.expect(t, "subscribe")
+
+	// drop the connection from the server side
+	require.NoError(t, server.lastConn().Close())
+
+	req := server.expect(t, "subscribe")
+	require.Equal(t, "trades", req.Subscription.Type)
+	require.Equal(t, "ETH", req.Subscription.Coin)
+
+	server.send(t, "trades", []Trade{{Coin: "ETH", Px: "3001", Tid: 1}})
+	require.Equal(t, "3001", receive(t, sub.C)[0].Px)
+}
+
+func TestPing(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	client.PingInterval = 10 * time.Millisecond
+	require.NoError(t, client.Connect(context.Background()))
+
+	server.expect(t, "ping")
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	require.NoError(t, client.Connect(context.Background()))
+
+	sub, err := client.SubscribeBbo("ETH")
+	require.NoError(t, err)
+	require.NoError(t, client.Close())
+
+	_, ok := <-sub.C
+	require.False(t, ok)
+
+	_, err = client.SubscribeBbo("BTC")
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+func TestUserlessChannelsRejectOtherUser(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	require.NoError(t, client.Connect(context.Background()))
+
+	first, err := client.SubscribeOrderUpdates("0xABC")
+	require.NoError(t, err)
+	server.expect(t, "subscribe")
+	// the same user may subscribe again, messages are delivered to both subscriptions
+	second, err := client.SubscribeOrderUpdates("0xabc")
+	require.NoError(t, err)
+
+	_, err = client.SubscribeOrderUpdates("0xDEF")
+	require.ErrorIs(t, err, ErrOtherUserSubscribed)
+	_, err = client.SubscribeUserFills("0xDEF", false)
+	require.NoError(t, err)
+	events, err := client.SubscribeUserEvents("0xDEF")
+	require.NoError(t, err)
+	_, err = client.SubscribeUserEvents("0xABC")
+	require.ErrorIs(t, err, ErrOtherUserSubscribed)
+
+	server.send(t, "orderUpdates", []OrderUpdate{{Status: "open"}})
+	require.Equal(t, "open", receive(t, first.C)[0].Status)
+	require.Equal(t, "open", receive(t, second.C)[0].Status)
+
+	// once the first user is gone another one can subscribe
+	require.NoError(t, events.Unsubscribe())
+	_, err = client.SubscribeUserEvents("0xABC")
+	require.NoError(t, err)
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	client.BufferSize = 1
+	require.NoError(t, client.Connect(context.Background()))
+
+	slow, err := client.SubscribeBbo("ETH")
+	require.NoError(t, err)
+	server.expect(t, "subscribe")
+	for i := int64(1); i <= 3; i++ {
+		server.send(t, "bbo", Bbo{Coin: "ETH", Time: i})
+	}
+
+	// post responses are read while the slow subscription is not
+	result := make(chan error, 1)
+	go func() {
+		_, err := NewPostAPI(client).Post(context.Background(), "/info", map[string]string{"type": "allMids"})
+		result <- err
+	}()
+	server.expect(t, "post")
+	server.send(t, "post", map[string]any{"id": 1, "response": map[string]any{"type": "info", "payload": map[string]any{"data": map[string]string{}}}})
+	select {
+	case err := <-result:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("post response not received")
+	}
+
+	req := server.expect(t, "unsubscribe")
+	require.Equal(t, "bbo", req.Subscription.Type)
+	require.Equal(t, int64(1), receive(t, slow.C).Time)
+	_, ok := <-slow.C
+	require.False(t, ok)
+}
@@ -0,0 +1,153 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mfgmateus/hyperliquid-go-sdk/v3/hyperliquid"
+)
+
+type postRequest struct {
+	Method  string      `json:"method"`
+	Id      int64       `json:"id"`
+	Request postPayload `json:"request"`
+}
+
+type postPayload struct {
+	Type    string `json:"type"`
+	Payload any    `json:"payload"`
+}
+
+type postMessage struct {
+	Id       int64 `json:"id"`
+	Response struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	} `json:"response"`
+}
+
+type postResult struct {
+	data json.RawMessage
+	err  error
+}
+
+// PostAPI implements hyperliquid.API over the websocket connection of a Client, so info requests and
+// signed actions avoid a new HTTPS request each. The client must be connected before posting.
+type PostAPI struct {
+	client *Client
+}
+
+func NewPostAPI(client *Client) hyperliquid.API {
+	return &PostAPI{
+		client: client,
+	}
+}
+
+// Post sends the payload of an "/info" or "/exchange" request and waits for its response,
+// returning it in the same shape as the REST endpoints.
+func (a *PostAPI) Post(ctx context.Context, path string, payload any) (json.RawMessage, error) {
+	switch path {
+	case "/info":
+		res, err := a.client.post(ctx, "info", payload)
+		if err != nil {
+			return nil, err
+		}
+		var info struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err = json.Unmarshal(res, &info); err != nil {
+			return nil, &hyperliquid.DecodeError{Body: res, Err: err}
+		}
+		return info.Data, nil
+	case "/exchange":
+		return a.client.post(ctx, "action", payload)
+	default:
+		return nil, fmt.Errorf("path %s can't be posted over websocket", path)
+	}
+}
+
+func (a *PostAPI) IsMainnet() bool {
+	return a.client.url == MainnetUrl
+}
+
+func (c *Client) post(ctx context.Context, requestType string, payload any) (json.RawMessage, error) {
+	result := make(chan postResult, 1)
+
+	c.mu.Lock()
+	if c.conn == nil {
+		c.mu.Unlock()
+		return nil, &hyperliquid.TransportError{Path: c.url, Err: ErrClosed}
+	}
+	c.nextPostId++
+	id := c.nextPostId
+	c.pending[id] = result
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	req := postRequest{
+		Method: "post",
+		Id:     id,
+		Request: postPayload{
+			Type:    requestType,
+			Payload: payload,
+		},
+	}
+	if err := c.write(req); err != nil {
+		return nil, &hyperliquid.TransportError{Path: c.url, Err: err}
+	}
+
+	select {
+	case res := <-result:
+		return res.data, res.err
+	case <-ctx.Done():
+		return nil, &hyperliquid.TransportError{Path: c.url, Err: ctx.Err()}
+	case <-c.ctx.Done():
+		return nil, &hyperliquid.TransportError{Path: c.url, Err: ErrClosed}
+	}
+}
+
+func (c *Client) handlePost(data json.RawMessage) {
+	var msg postMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.logger.LogErr(c.ctx, fmt.Sprintf("Failed to parse post message: %s", data), err)
+		return
+	}
+
+	c.mu.Lock()
+	result, ok := c.pending[msg.Id]
+	delete(c.pending, msg.Id)
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if msg.Response.Type == "error" {
+		var message string
+		if err := json.Unmarshal(msg.Response.Payload, &message); err != nil {
+			message = string(msg.Response.Payload)
+		}
+		result <- postResult{err: &hyperliquid.ExchangeError{Status: "error", Message: message}}
+		return
+	}
+	result <- postResult{data: msg.Response.Payload}
+}
+
+// failPending fails the requests waiting on a lost connection. Actions among them may or may not have been executed.
+func (c *Client) failPending(err error) {
+	if err == nil {
+		err = errors.New("connection lost")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, result := range c.pending {
+		result <- postResult{err: &hyperliquid.TransportError{Path: c.url, Err: err}}
+		delete(c.pending, id)
+	}
+}
@@ -0,0 +1,130 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mfgmateus/hyperliquid-go-sdk/v3/hyperliquid"
+	"github.com/stretchr/testify/require"
+)
+
+type postRequestMessage struct {
+	Method  string `json:"method"`
+	Id      int64  `json:"id"`
+	Request struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	} `json:"request"`
+}
+
+// respond answers post requests received by the server with the given response type and payload
+func (s *testServer) respond(t *testing.T, conn int, responseType string, payload string) postRequestMessage {
+	var req postRequestMessage
+	s.mu.Lock()
+	c := s.conns[conn]
+	s.mu.Unlock()
+	require.NoError(t, c.ReadJSON(&req))
+
+	data := map[string]any{
+		"id": req.Id,
+		"response": map[string]any{
+			"type":    responseType,
+			"payload": json.RawMessage(payload),
+		},
+	}
+	raw, _ := json.Marshal(data)
+	require.NoError(t, c.WriteJSON(wsMessage{Channel: "post", Data: raw}))
+	return req
+}
+
+// newPostServer starts a server which leaves reading to the test, so post requests can be answered
+func newPostServer(t *testing.T) (*testServer, *Client) {
+	server := newServer(t, false)
+	client := newTestClient(t, server)
+	require.NoError(t, client.Connect(context.Background()))
+	require.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.conns) == 1
+	}, time.Second, 5*time.Millisecond)
+	return server, client
+}
+
+func TestPostInfo(t *testing.T) {
+	server, client := newPostServer(t)
+	api := NewPostAPI(client)
+
+	go server.respond(t, 0, "info", `{"type":"allMids","data":{"ETH":"3000.1"}}`)
+
+	res, err := api.Post(context.Background(), "/info", map[string]string{"type": "allMids"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ETH":"3000.1"}`, string(res))
+	require.False(t, api.IsMainnet())
+}
+
+func TestPostAction(t *testing.T) {
+	server, client := newPostServer(t)
+	api := NewPostAPI(client)
+
+	requests := make(chan postRequestMessage, 1)
+	go func() {
+		requests <- server.respond(t, 0, "action", `{"status":"ok","response":{"type":"default"}}`)
+	}()
+
+	payload := hyperliquid.ExchangeRequest{
+		Action: hyperliquid.UpdateLeverageAction{Type: "updateLeverage", Asset: 1, Leverage: 5},
+		Nonce:  1,
+	}
+	res, err := api.Post(context.Background(), "/exchange", payload)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status":"ok","response":{"type":"default"}}`, string(res))
+
+	req := <-requests
+	require.Equal(t, "post", req.Method)
+	require.Equal(t, "action", req.Request.Type)
+	expected, _ := json.Marshal(payload)
+	require.JSONEq(t, string(expected), string(req.Request.Payload))
+}
+
+func TestPostError(t *testing.T) {
+	server, client := newPostServer(t)
+	api := NewPostAPI(client)
+
+	go server.respond(t, 0, "error", `"Invalid request"`)
+
+	_, err := api.Post(context.Background(), "/info", map[string]string{"type": "unknown"})
+	var exchangeErr *hyperliquid.ExchangeError
+	require.True(t, errors.As(err, &exchangeErr))
+	require.Equal(t, "Invalid request", exchangeErr.Message)
+}
+
+func TestPostDeadline(t *testing.T) {
+	_, client := newPostServer(t)
+	api := NewPostAPI(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := api.Post(ctx, "/info", map[string]string{"type": "allMids"})
+	var transportErr *hyperliquid.TransportError
+	require.True(t, errors.As(err, &transportErr))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPostConnectionLost(t *testing.T) {
+	server, client := newPostServer(t)
+	api := NewPostAPI(client)
+
+	go func() {
+		var req postRequestMessage
+		_ = server.lastConn().ReadJSON(&req)
+		_ = server.lastConn().Close()
+	}()
+
+	_, err := api.Post(context.Background(), "/exchange", map[string]string{})
+	var transportErr *hyperliquid.TransportError
+	require.True(t, errors.As(err, &transportErr))
+}
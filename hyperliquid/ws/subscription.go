@@ -0,0 +1,115 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Subscription delivers the messages of a channel on C until Unsubscribe or Client.Close is called,
+// after which C is closed. A subscription whose buffer of Client.BufferSize messages is full is dropped,
+// closing C, so a slow reader never delays the other subscriptions and post responses.
+type Subscription[T any] struct {
+	C <-chan T
+
+	client *Client
+	sub    *subscriber
+}
+
+func (s *Subscription[T]) Unsubscribe() error {
+	return s.client.removeSubscriber(s.sub)
+}
+
+func subscribe[T any](c *Client, req SubscriptionRequest) (*Subscription[T], error) {
+	ch := make(chan T, c.BufferSize)
+
+	var (
+		mu     sync.Mutex
+		once   sync.Once
+		closed bool
+	)
+
+	sub := &subscriber{
+		request: req,
+		deliver: func(data json.RawMessage) bool {
+			var value T
+			if err := json.Unmarshal(data, &value); err != nil {
+				c.logger.LogErr(c.ctx, fmt.Sprintf("Failed to parse %s message: %s", req.Type, data), err)
+				return true
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if closed {
+				return true
+			}
+			select {
+			case ch <- value:
+				return true
+			default:
+				return false
+			}
+		},
+		close: func() {
+			once.Do(func() {
+				mu.Lock()
+				closed = true
+				close(ch)
+				mu.Unlock()
+			})
+		},
+	}
+
+	if err := c.addSubscriber(sub); err != nil {
+		return nil, err
+	}
+
+	return &Subscription[T]{
+		C:      ch,
+		client: c,
+		sub:    sub,
+	}, nil
+}
+
+func (c *Client) SubscribeAllMids() (*Subscription[AllMids], error) {
+	return subscribe[AllMids](c, SubscriptionRequest{Type: "allMids"})
+}
+
+// SubscribeL2Book streams book snapshots for a coin. nSigFigs and mantissa are optional and aggregate the levels.
+func (c *Client) SubscribeL2Book(coin string, nSigFigs *int, mantissa *int) (*Subscription[L2Book], error) {
+	return subscribe[L2Book](c, SubscriptionRequest{Type: "l2Book", Coin: coin, NSigFigs: nSigFigs, Mantissa: mantissa})
+}
+
+func (c *Client) SubscribeTrades(coin string) (*Subscription[[]Trade], error) {
+	return subscribe[[]Trade](c, SubscriptionRequest{Type: "trades", Coin: coin})
+}
+
+// SubscribeCandle streams candles for a coin, interval is one of "1m", "3m", "5m", "15m", "30m", "1h", "2h",
+// "4h", "8h", "12h", "1d", "3d", "1w" or "1M"
+func (c *Client) SubscribeCandle(coin string, interval string) (*Subscription[Candle], error) {
+	return subscribe[Candle](c, SubscriptionRequest{Type: "candle", Coin: coin, Interval: interval})
+}
+
+func (c *Client) SubscribeOrderUpdates(address string) (*Subscription[[]OrderUpdate], error) {
+	return subscribe[[]OrderUpdate](c, SubscriptionRequest{Type: "orderUpdates", User: address})
+}
+
+func (c *Client) SubscribeUserFills(address string, aggregateByTime bool) (*Subscription[UserFills], error) {
+	req := SubscriptionRequest{Type: "userFills", User: address}
+	if aggregateByTime {
+		req.AggregateByTime = &aggregateByTime
+	}
+	return subscribe[UserFills](c, req)
+}
+
+func (c *Client) SubscribeUserEvents(address string) (*Subscription[UserEvent], error) {
+	return subscribe[UserEvent](c, SubscriptionRequest{Type: "userEvents", User: address})
+}
+
+func (c *Client) SubscribeUserFundings(address string) (*Subscription[UserFundings], error) {
+	return subscribe[UserFundings](c, SubscriptionRequest{Type: "userFundings", User: address})
+}
+
+func (c *Client) SubscribeBbo(coin string) (*Subscription[Bbo], error) {
+	return subscribe[Bbo](c, SubscriptionRequest{Type: "bbo", Coin: coin})
+}
@@ -0,0 +1,178 @@
+package ws
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mfgmateus/hyperliquid-go-sdk/v3/hyperliquid"
+)
+
+// SubscriptionRequest is the subscription object sent in subscribe and unsubscribe messages
+type SubscriptionRequest struct {
+	Type            string `json:"type"`
+	Coin            string `json:"coin,omitempty"`
+	User            string `json:"user,omitempty"`
+	Interval        string `json:"interval,omitempty"`
+	NSigFigs        *int   `json:"nSigFigs,omitempty"`
+	Mantissa        *int   `json:"mantissa,omitempty"`
+	AggregateByTime *bool  `json:"aggregateByTime,omitempty"`
+}
+
+// identifier matches a subscription with the messages it receives.
+// orderUpdates and userEvents messages carry no user, so only one user can be subscribed to them per connection,
+// which addSubscriber enforces.
+func (r SubscriptionRequest) identifier() string {
+	switch r.Type {
+	case "allMids", "orderUpdates", "userEvents":
+		return r.Type
+	case "l2Book", "trades", "bbo":
+		return r.Type + ":" + r.Coin
+	case "candle":
+		return r.Type + ":" + r.Coin + "," + r.Interval
+	case "userFills", "userFundings":
+		return r.Type + ":" + strings.ToLower(r.User)
+	default:
+		return r.Type
+	}
+}
+
+// userless is true for the user channels whose messages don't identify the user
+func (r SubscriptionRequest) userless() bool {
+	return r.Type == "orderUpdates" || r.Type == "userEvents"
+}
+
+type wsRequest struct {
+	Method       string               `json:"method"`
+	Subscription *SubscriptionRequest `json:"subscription,omitempty"`
+}
+
+type wsMessage struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// messageIdentifier returns the identifier of the subscription a message belongs to
+func messageIdentifier(msg wsMessage) (string, error) {
+	var data struct {
+		Coin     string `json:"coin"`
+		User     string `json:"user"`
+		Symbol   string `json:"s"`
+		Interval string `json:"i"`
+	}
+
+	switch msg.Channel {
+	case "allMids", "orderUpdates":
+		return msg.Channel, nil
+	case "user":
+		return "userEvents", nil
+	case "trades":
+		var trades []Trade
+		if err := json.Unmarshal(msg.Data, &trades); err != nil {
+			return "", err
+		}
+		if len(trades) == 0 {
+			return "", nil
+		}
+		return msg.Channel + ":" + trades[0].Coin, nil
+	}
+
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		return "", err
+	}
+
+	switch msg.Channel {
+	case "l2Book", "bbo":
+		return msg.Channel + ":" + data.Coin, nil
+	case "candle":
+		return msg.Channel + ":" + data.Symbol + "," + data.Interval, nil
+	case "userFills", "userFundings":
+		return msg.Channel + ":" + strings.ToLower(data.User), nil
+	default:
+		return msg.Channel, nil
+	}
+}
+
+type AllMids struct {
+	Mids map[string]string `json:"mids"`
+}
+
+type L2Level = hyperliquid.L2Level
+
+type L2Book = hyperliquid.L2Book
+
+type Trade struct {
+	Coin  string    `json:"coin"`
+	Side  string    `json:"side"`
+	Px    string    `json:"px"`
+	Sz    string    `json:"sz"`
+	Hash  string    `json:"hash"`
+	Time  int64     `json:"time"`
+	Tid   int64     `json:"tid"`
+	Users [2]string `json:"users"`
+}
+
+type Candle = hyperliquid.Candle
+
+type BasicOrder struct {
+	Coin      string  `json:"coin"`
+	Side      string  `json:"side"`
+	LimitPx   string  `json:"limitPx"`
+	Sz        string  `json:"sz"`
+	Oid       int64   `json:"oid"`
+	Timestamp int64   `json:"timestamp"`
+	OrigSz    string  `json:"origSz"`
+	Cloid     *string `json:"cloid"`
+}
+
+type OrderUpdate struct {
+	Order           BasicOrder `json:"order"`
+	Status          string     `json:"status"`
+	StatusTimestamp int64      `json:"statusTimestamp"`
+}
+
+type UserFills struct {
+	IsSnapshot bool                    `json:"isSnapshot"`
+	User       string                  `json:"user"`
+	Fills      []hyperliquid.OrderFill `json:"fills"`
+}
+
+type UserFunding struct {
+	Time        int64  `json:"time"`
+	Coin        string `json:"coin"`
+	Usdc        string `json:"usdc"`
+	Szi         string `json:"szi"`
+	FundingRate string `json:"fundingRate"`
+}
+
+type UserFundings struct {
+	IsSnapshot bool          `json:"isSnapshot"`
+	User       string        `json:"user"`
+	Fundings   []UserFunding `json:"fundings"`
+}
+
+type UserLiquidation struct {
+	Lid                    int64  `json:"lid"`
+	Liquidator             string `json:"liquidator"`
+	LiquidatedUser         string `json:"liquidated_user"`
+	LiquidatedNtlPos       string `json:"liquidated_ntl_pos"`
+	LiquidatedAccountValue string `json:"liquidated_account_value"`
+}
+
+type NonUserCancel struct {
+	Coin string `json:"coin"`
+	Oid  int64  `json:"oid"`
+}
+
+// UserEvent holds exactly one of the event kinds sent on the user channel
+type UserEvent struct {
+	Fills         []hyperliquid.OrderFill `json:"fills,omitempty"`
+	Funding       *UserFunding            `json:"funding,omitempty"`
+	Liquidation   *UserLiquidation        `json:"liquidation,omitempty"`
+	NonUserCancel []NonUserCancel         `json:"nonUserCancel,omitempty"`
+}
+
+type Bbo struct {
+	Coin string      `json:"coin"`
+	Time int64       `json:"time"`
+	Bbo  [2]*L2Level `json:"bbo"`
+}